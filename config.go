@@ -10,41 +10,76 @@ import (
 )
 
 type config struct {
-	Numcaps            int    `json:"numcaps"`
-	Columns            int    `json:"columns"`
-	Padding            int    `json:"padding"`
-	Width              int    `json:"width"`
-	Font_All           string `json:"font_all"`
-	Font_Size          int    `json:"font_size"`
-	Disable_Timestamps bool   `json:"disable_timestamps"`
-	Verbose            bool   `json:"verbose"`
-	Single_Images      bool   `json:"single_images"`
-	Bg_Header          string `json:"bg_header"`
-	Fg_Header          string `json:"fg_header"`
-	Bg_Content         string `json:"bg_content"`
-	Header_Image       string `json:"header_image"`
-	Skip_Blank         bool   `json:"skip_blank"`
-	Header             bool   `json:"header"`
-	Header_Meta        bool   `json:"header_meta"`
-	Skip_Credits       bool   `json:"skip_credits"`
-	Filter             string `json:"filter"`
-	Filename           string `json:"filename"`
-	From               string `json:"from"`
-	To                 string `json:"to"`
-	Skip_Existing      bool   `json:"skip_existing"`
-	Overwrite          bool   `json:"overwrite"`
-	SFW                bool   `json:"sfw"`
-	Watermark          string `json:"watermark"`
-	Fast               bool   `json:"fast"`
-	Watermark_All      string `json:"watermark_all"`
-	Comment            string `json:"comment"`
-	Skip_Blurry        bool   `json:"skip_blurry"`
-	Blur_Threshold     int    `json:"blur_threshold"`
-	Blank_Threshold    int    `json:"blank_threshold"`
-	Webvtt             bool   `json:"webvtt"`
-	Vtt                bool   `json:"vtt"`
-	Upload             bool   `json:"upload"`
-	Upload_URL         string `json:"upload_url"`
+	Numcaps             int      `json:"numcaps"`
+	Columns             int      `json:"columns"`
+	Padding             int      `json:"padding"`
+	Width               int      `json:"width"`
+	Sheet_Width         int      `json:"sheet_width"`
+	Sheet_Max_Height    int      `json:"sheet_max_height"`
+	Sheet_Aspect        string   `json:"sheet_aspect"`
+	Layout              string   `json:"layout"`
+	Featured            string   `json:"featured"`
+	Hero_Size           string   `json:"hero_size"`
+	Font_All            string   `json:"font_all"`
+	Font_Size           int      `json:"font_size"`
+	Disable_Timestamps  bool     `json:"disable_timestamps"`
+	Timestamp_Format    string   `json:"timestamp_format"`
+	Verbose             bool     `json:"verbose"`
+	Single_Images       bool     `json:"single_images"`
+	Bg_Header           string   `json:"bg_header"`
+	Fg_Header           string   `json:"fg_header"`
+	Bg_Content          string   `json:"bg_content"`
+	Header_Image        string   `json:"header_image"`
+	Skip_Blank          bool     `json:"skip_blank"`
+	Header              bool     `json:"header"`
+	Header_Meta         bool     `json:"header_meta"`
+	Skip_Credits        bool     `json:"skip_credits"`
+	Skip_Intro          bool     `json:"skip_intro"`
+	Filter              string   `json:"filter"`
+	Fancy_Min_Angle     int      `json:"fancy_min_angle"`
+	Fancy_Max_Angle     int      `json:"fancy_max_angle"`
+	Seed                int64    `json:"seed"`
+	Filename            string   `json:"filename"`
+	From                string   `json:"from"`
+	To                  string   `json:"to"`
+	Skip_Existing       bool     `json:"skip_existing"`
+	Overwrite           bool     `json:"overwrite"`
+	SFW                 bool     `json:"sfw"`
+	Censor              string   `json:"censor"`
+	Censor_Area         string   `json:"censor_area"`
+	Censor_Label        string   `json:"censor_label"`
+	Watermark           string   `json:"watermark"`
+	Fast                bool     `json:"fast"`
+	Fast_Scale          bool     `json:"fast_scale"`
+	Auto_Crop           bool     `json:"auto_crop"`
+	Watermark_All       string   `json:"watermark_all"`
+	Comment             string   `json:"comment"`
+	Skip_Blurry         bool     `json:"skip_blurry"`
+	Blur_Threshold      float64  `json:"blur_threshold"`
+	Blank_Threshold     int      `json:"blank_threshold"`
+	Skip_Duplicates     bool     `json:"skip_duplicates"`
+	Duplicate_Threshold int      `json:"duplicate_threshold"`
+	Reject              []string `json:"reject"`
+	Analyze_Format      string   `json:"analyze_format"`
+	Analyze_Samples     int      `json:"analyze_samples"`
+	Retries             int      `json:"retries"`
+	Retry_Step          string   `json:"retry_step"`
+	Retry_Direction     string   `json:"retry_direction"`
+	Retry_Fallback      string   `json:"retry_fallback"`
+	Candidates          int      `json:"candidates"`
+	Candidate_Step      string   `json:"candidate_step"`
+	Webvtt              bool     `json:"webvtt"`
+	Vtt                 bool     `json:"vtt"`
+	Upload              bool     `json:"upload"`
+	Upload_URL          string   `json:"upload_url"`
+	Workers             int      `json:"workers"`
+	Jobs                int      `json:"jobs"`
+	Select              string   `json:"select"`
+	Scene_Samples       int      `json:"scene_samples"`
+	Chapter_Caps        int      `json:"chapter_caps"`
+	Range               string   `json:"range"`
+	At                  string   `json:"at"`
+	At_File             string   `json:"at_file"`
 }
 
 var C config
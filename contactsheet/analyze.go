@@ -0,0 +1,183 @@
+package contactsheet
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+	"github.com/koyachi/go-nude"
+	"gitlab.com/opennota/screengen"
+)
+
+// FrameScores are the scores of a single analyzed frame, they can be used
+// to calibrate the thresholds of the rejectors
+type FrameScores struct {
+	Timestamp  int64   `json:"timestamp"`          // position in the video in milliseconds
+	Blank      int     `json:"blank"`              // percentage of blank pixels, see Options.BlankThreshold
+	Sharpness  float64 `json:"sharpness"`          // variance of the laplacian, see Options.BlurThreshold
+	Nude       bool    `json:"nude"`               // result of the nudity detection
+	Brightness float64 `json:"brightness"`         // mean luma from 0 to 255
+	Duplicate  int     `json:"duplicate"`          // smallest perceptual hash distance (0-64) to an earlier frame, see Options.DuplicateThreshold
+	Quality    float64 `json:"quality"`            // rating from 0 to 1 used for Options.Candidates
+	Rejected   string  `json:"rejected,omitempty"` // the first rejector of Options.Reject rejecting the frame
+}
+
+// Analysis holds the scores of all analyzed frames of a video
+type Analysis struct {
+	File     string        `json:"file"`
+	Duration int64         `json:"duration"` // length of the video in milliseconds
+	Frames   []FrameScores `json:"frames"`
+}
+
+// Analyze takes frames at the timestamps Generate would use (or
+// Options.AnalyzeSamples frames spread the same way) and scores them,
+// without retrying rejected frames or creating a contact sheet
+func Analyze(ctx context.Context, fn string, opts Options) (res *Analysis, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = nil
+			err = fmt.Errorf("panic while processing %s: %v", fn, r)
+		}
+	}()
+
+	g, err := newGenerator(fn, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AnalyzeSamples > 0 {
+		g.numcaps = opts.AnalyzeSamples
+	}
+
+	g.media, err = g.probe()
+	if err != nil {
+		return nil, err
+	}
+	defer g.media.close()
+
+	pool := g.newDecoderPool()
+	defer pool.close()
+	stamps, err := g.planScreenshots(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	gens := pool.get(len(stamps))
+
+	g.log.Infof("analyzing %d frames", len(stamps))
+	g.tile = g.tileSize(g.frameBounds())
+	sequential := g.hasRejectors(true)
+	frames := make([]FrameScores, len(stamps))
+	hashes := make([]uint64, len(stamps))
+	rejected := make([]int, len(stamps))       // index of the first rejecting rejector which isn't sequential
+	thumbs := make([]image.Image, len(stamps)) // frames at screenshot size for the sequential rejectors
+	err = g.parallel(ctx, gens, len(stamps), func(gen *screengen.Generator, i int) error {
+		img, err := g.decode(gen, stamps[i], false)
+		if err != nil {
+			return fmt.Errorf("can't analyze frame at %s: %v", formatTimestamp(stamps[i]), err)
+		}
+		isNude, err := nude.IsImageNude(img)
+		if err != nil {
+			g.log.Errorf("nudity detection of frame at %s failed: %v", formatTimestamp(stamps[i]), err)
+		}
+		// Generate compares the frames at their size on the contact sheet
+		thumb := g.shrink(img, false)
+		hashes[i] = dHash(thumb)
+		rejected[i] = g.firstRejector(img, false, len(g.rejectors))
+		if sequential {
+			thumbs[i] = thumb
+		}
+		frames[i] = FrameScores{
+			Timestamp:  stamps[i],
+			Blank:      blankPercent(img),
+			Sharpness:  sharpness(img),
+			Nude:       isNude,
+			Brightness: meanLuma(img),
+			Quality:    g.quality(img),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// duplicates depend on the frames before, so the chain runs in order
+	for i := range frames {
+		frames[i].Duplicate = 64
+		for _, h := range hashes[:i] {
+			if d := bits.OnesCount64(hashes[i] ^ h); d < frames[i].Duplicate {
+				frames[i].Duplicate = d
+			}
+		}
+		// only sequential rejectors before the one which already rejected
+		// the frame can reject it first
+		k := rejected[i]
+		if sequential {
+			k = g.firstRejector(thumbs[i], true, k)
+			// every analyzed frame counts as used
+			g.accept(thumbs[i])
+			thumbs[i] = nil
+		}
+		if k < len(g.rejectors) {
+			frames[i].Rejected = g.rejectors[k].Name()
+		}
+	}
+
+	return &Analysis{File: fn, Duration: g.media.Duration, Frames: frames}, nil
+}
+
+// returns the index of the first of the first n rejectors rejecting img or
+// n if none does, only the sequential or only the other rejectors are used
+func (g *generator) firstRejector(img image.Image, sequential bool, n int) int {
+	for k, r := range g.rejectors[:n] {
+		if _, ok := r.(SequentialRejector); ok != sequential {
+			continue
+		}
+		if _, reject, err := r.Check(img); err == nil && reject {
+			return k
+		}
+	}
+	return n
+}
+
+// returns the mean luma of img from 0 to 255
+func meanLuma(img image.Image) float64 {
+	if img.Bounds().Dx() > qualityWidth {
+		img = imaging.Resize(img, qualityWidth, 0, imaging.Box)
+	}
+	nrgba := imaging.Clone(img)
+	var sum, n float64
+	for i := 0; i+3 < len(nrgba.Pix); i += 4 {
+		sum += 0.299*float64(nrgba.Pix[i]) + 0.587*float64(nrgba.Pix[i+1]) + 0.114*float64(nrgba.Pix[i+2])
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
+
+// CSV returns the scores as CSV with a header line, one frame per line
+func (a *Analysis) CSV() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"timestamp", "time", "blank", "sharpness", "nude", "brightness", "duplicate", "quality", "rejected"})
+	for _, f := range a.Frames {
+		w.Write([]string{
+			strconv.FormatInt(f.Timestamp, 10),
+			formatTimestampMS(f.Timestamp),
+			strconv.Itoa(f.Blank),
+			strconv.FormatFloat(f.Sharpness, 'f', 2, 64),
+			strconv.FormatBool(f.Nude),
+			strconv.FormatFloat(f.Brightness, 'f', 2, 64),
+			strconv.Itoa(f.Duplicate),
+			strconv.FormatFloat(f.Quality, 'f', 3, 64),
+			f.Rejected,
+		})
+	}
+	w.Flush()
+	return buf.String()
+}
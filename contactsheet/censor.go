@@ -0,0 +1,136 @@
+package contactsheet
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/koyachi/go-nude"
+)
+
+const (
+	censorBlur       = 0.05 // sigma of the blur relative to the image width
+	censorPixelSize  = 0.04 // size of a pixelation block relative to the image width
+	censorSkinMargin = 0.1  // margin added around skin regions relative to their size
+)
+
+// the parts of a decoded frame Options.Censor hides, relative to the
+// frame's top left corner. A frame without areas is shown as it is.
+type nudity struct {
+	size  image.Point // of the frame the areas were detected in
+	areas []image.Rectangle
+}
+
+// runs the nudity detection on a decoded frame, before it is scaled to its
+// size on the contact sheet, so it sees the same frame as the nude
+// rejector. Depending on Options.CensorArea the whole frame or only the
+// skin regions are censored.
+func (g *generator) detectNudity(img image.Image) nudity {
+	if g.opts.Censor == "" {
+		return nudity{}
+	}
+	d := nude.NewDetector(img)
+	isNude, err := d.Parse()
+	if err != nil {
+		g.log.Errorf("nudity detection failed: %v", err)
+		return nudity{}
+	}
+	if !isNude {
+		return nudity{}
+	}
+
+	b := img.Bounds()
+	n := nudity{size: b.Size(), areas: []image.Rectangle{b}}
+	if g.opts.CensorArea == "skin" {
+		n.areas = skinAreas(d.SkinRegions, b)
+	}
+	for i := range n.areas {
+		n.areas[i] = n.areas[i].Sub(b.Min)
+	}
+	return n
+}
+
+// returns the areas for the frame scaled (and, for featured screenshots,
+// center cropped) to size
+func (n nudity) areasIn(size image.Point) []image.Rectangle {
+	if n.size.X <= 0 || n.size.Y <= 0 {
+		return nil
+	}
+	scale := math.Max(float64(size.X)/float64(n.size.X), float64(size.Y)/float64(n.size.Y))
+	offset := image.Pt(
+		int(math.Round((float64(n.size.X)*scale-float64(size.X))/2)),
+		int(math.Round((float64(n.size.Y)*scale-float64(size.Y))/2)),
+	)
+	bounds := image.Rect(0, 0, size.X, size.Y)
+	var areas []image.Rectangle
+	for _, a := range n.areas {
+		r := image.Rect(
+			int(math.Floor(float64(a.Min.X)*scale)), int(math.Floor(float64(a.Min.Y)*scale)),
+			int(math.Ceil(float64(a.Max.X)*scale)), int(math.Ceil(float64(a.Max.Y)*scale)),
+		).Sub(offset)
+		if r = r.Intersect(bounds); !r.Empty() {
+			areas = append(areas, r)
+		}
+	}
+	return areas
+}
+
+// censors the areas of n in the screenshot img with Options.Censor and
+// reports if it did
+func (g *generator) censor(img image.Image, n nudity) (image.Image, bool) {
+	areas := n.areasIn(img.Bounds().Size())
+	if len(areas) == 0 {
+		return img, false
+	}
+
+	dst := imaging.Clone(img)
+	w := dst.Bounds().Dx()
+	for _, a := range areas {
+		part := imaging.Crop(dst, a)
+		switch g.opts.Censor {
+		case "blur":
+			part = imaging.Blur(part, censorBlur*float64(w))
+		case "pixelate":
+			part = pixelate(part, int(censorPixelSize*float64(w)))
+		}
+		dst = imaging.Paste(dst, part, a.Min)
+	}
+
+	if g.opts.CensorLabel != "" {
+		label := g.drawTimestamp(g.opts.CensorLabel)
+		pos := image.Pt((dst.Bounds().Dx()-label.Bounds().Dx())/2, (dst.Bounds().Dy()-label.Bounds().Dy())/2)
+		dst = imaging.Overlay(dst, label, pos, 1.0)
+	}
+	return dst, true
+}
+
+// returns the bounding boxes of the skin regions with some margin, clipped
+// to bounds
+func skinAreas(regions nude.Regions, bounds image.Rectangle) []image.Rectangle {
+	var areas []image.Rectangle
+	for _, region := range regions {
+		if len(region) == 0 {
+			continue
+		}
+		r := image.Rect(region[0].X, region[0].Y, region[0].X+1, region[0].Y+1)
+		for _, p := range region[1:] {
+			r = r.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+		}
+		r = r.Inset(-int(censorSkinMargin * float64(r.Dx()+r.Dy()) / 2))
+		if r = r.Intersect(bounds); !r.Empty() {
+			areas = append(areas, r)
+		}
+	}
+	return areas
+}
+
+// scales img down so every block of size px becomes a single pixel and
+// back up again
+func pixelate(img image.Image, px int) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if px < 2 {
+		px = 2
+	}
+	small := imaging.Resize(img, (w+px-1)/px, (h+px-1)/px, imaging.Box)
+	return imaging.Resize(small, w, h, imaging.NearestNeighbor)
+}
@@ -0,0 +1,33 @@
+package contactsheet
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestNudityAreasIn(t *testing.T) {
+	n := nudity{
+		size:  image.Pt(200, 100),
+		areas: []image.Rectangle{image.Rect(20, 10, 60, 30), image.Rect(150, 0, 200, 100)},
+	}
+	tests := []struct {
+		name string
+		size image.Point
+		want []image.Rectangle
+	}{
+		{"same size", image.Pt(200, 100), n.areas},
+		{"scaled", image.Pt(100, 50), []image.Rectangle{image.Rect(10, 5, 30, 15), image.Rect(75, 0, 100, 50)}},
+		// featured screenshots are center cropped, the second area is cut off
+		{"filled", image.Pt(100, 100), []image.Rectangle{image.Rect(0, 10, 10, 30)}},
+	}
+	for _, tt := range tests {
+		if got := n.areasIn(tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := (nudity{}).areasIn(image.Pt(100, 50)); got != nil {
+		t.Errorf("clean frame: got %v, want no areas", got)
+	}
+}
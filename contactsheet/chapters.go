@@ -0,0 +1,392 @@
+package contactsheet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Chapter is a chapter marker of the video container
+type Chapter struct {
+	Title string `json:"title"`
+	Start int64  `json:"start"` // start in milliseconds
+	End   int64  `json:"end"`   // end in milliseconds
+}
+
+// returns ChapterCaps timestamps per chapter between start and stop and
+// the title of the chapter for each of them
+func (g *generator) chapterTimestamps(start, stop int64) ([]int64, []string) {
+	caps := g.opts.ChapterCaps
+	if caps < 1 {
+		caps = 1
+	}
+
+	var stamps []int64
+	var titles []string
+	for _, c := range g.media.Chapters {
+		from, to := c.Start, c.End
+		if from < start {
+			from = start
+		}
+		if to > stop {
+			to = stop
+		}
+		if to <= from {
+			continue
+		}
+		step := (to - from) / int64(caps)
+		for i := 0; i < caps; i++ {
+			stamps = append(stamps, from+int64(i)*step+step/2)
+			titles = append(titles, c.Title)
+		}
+	}
+	g.log.Debugf("using %d of %d chapters", len(stamps)/caps, len(g.media.Chapters))
+	return stamps, titles
+}
+
+// chapters are stored in small elements, anything bigger is not read
+const maxChapterData = 16 << 20
+
+var errNoChapters = errors.New("no chapters found")
+
+// reads the chapter markers of a local matroska or mp4 file sorted by their
+// start, duration is used as end of the last chapter
+func readChapters(fn string, duration int64) ([]Chapter, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var chapters []Chapter
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".mkv", ".mka", ".mk3d", ".webm":
+		chapters, err = readMatroskaChapters(f)
+	case ".mp4", ".m4v", ".mov", ".m4a", ".3gp":
+		chapters, err = readMP4Chapters(f)
+	default:
+		return nil, errNoChapters
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// containers don't have to store the chapters in order
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	for i := range chapters {
+		if i < len(chapters)-1 {
+			chapters[i].End = chapters[i+1].Start
+		} else {
+			chapters[i].End = duration
+		}
+	}
+	return chapters, nil
+}
+
+// matroska element ids
+const (
+	mkvSegment          = 0x18538067
+	mkvSeekHead         = 0x114D9B74
+	mkvSeek             = 0x4DBB
+	mkvSeekID           = 0x53AB
+	mkvSeekPosition     = 0x53AC
+	mkvCluster          = 0x1F43B675
+	mkvChapters         = 0x1043A770
+	mkvEditionEntry     = 0x45B9
+	mkvChapterAtom      = 0xB6
+	mkvChapterTimeStart = 0x91
+	mkvChapterHidden    = 0x98
+	mkvChapterDisplay   = 0x80
+	mkvChapString       = 0x85
+)
+
+// a matroska element read into memory
+type ebmlElement struct {
+	id   uint64
+	data []byte
+}
+
+// reads a variable length integer, the length marker is kept for ids
+func readVint(r io.Reader, keepMarker bool) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, errors.New("invalid ebml integer")
+	}
+	if _, err := io.ReadFull(r, b[1:length]); err != nil {
+		return 0, err
+	}
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= uint64(0xFF >> uint(length))
+	}
+	for _, c := range b[1:length] {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// reads id and size of the next element
+func readElementHeader(r io.Reader) (uint64, uint64, error) {
+	id, err := readVint(r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := readVint(r, false)
+	return id, size, err
+}
+
+// splits the data of an element into its children
+func ebmlChildren(b []byte) []ebmlElement {
+	var children []ebmlElement
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		id, size, err := readElementHeader(r)
+		if err != nil || size > uint64(r.Len()) {
+			break
+		}
+		start := len(b) - r.Len()
+		children = append(children, ebmlElement{id, b[start : start+int(size)]})
+		r.Seek(int64(size), io.SeekCurrent)
+	}
+	return children
+}
+
+func ebmlUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// reads the chapters of the first edition of a matroska file
+func readMatroskaChapters(f io.ReadSeeker) ([]Chapter, error) {
+	// skip the EBML header
+	_, size, err := readElementHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(int64(size), io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	id, _, err := readElementHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if id != mkvSegment {
+		return nil, errors.New("no matroska segment found")
+	}
+	segmentStart, _ := f.Seek(0, io.SeekCurrent)
+
+	var chaptersPos int64 = -1
+	for {
+		id, size, err := readElementHeader(f)
+		if err != nil {
+			return nil, errNoChapters
+		}
+		switch id {
+		case mkvChapters:
+			return parseMatroskaChapters(f, size)
+		case mkvSeekHead:
+			if size > maxChapterData {
+				return nil, errNoChapters
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(f, data); err != nil {
+				return nil, err
+			}
+			for _, seek := range ebmlChildren(data) {
+				if seek.id != mkvSeek {
+					continue
+				}
+				var seekID, seekPos uint64
+				for _, e := range ebmlChildren(seek.data) {
+					switch e.id {
+					case mkvSeekID:
+						seekID = ebmlUint(e.data)
+					case mkvSeekPosition:
+						seekPos = ebmlUint(e.data)
+					}
+				}
+				if seekID == mkvChapters {
+					chaptersPos = int64(seekPos)
+				}
+			}
+			continue
+		case mkvCluster:
+			// the media data starts, chapters can only be found via the seek head
+			if chaptersPos < 0 {
+				return nil, errNoChapters
+			}
+			if _, err := f.Seek(segmentStart+chaptersPos, io.SeekStart); err != nil {
+				return nil, err
+			}
+			if id, size, err := readElementHeader(f); err == nil && id == mkvChapters {
+				return parseMatroskaChapters(f, size)
+			}
+			return nil, errNoChapters
+		}
+		// elements of unknown size can't be skipped
+		if size == 1<<56-1 {
+			return nil, errNoChapters
+		}
+		if _, err := f.Seek(int64(size), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parses the Chapters element of the given size at the current position
+func parseMatroskaChapters(r io.Reader, size uint64) ([]Chapter, error) {
+	if size > maxChapterData {
+		return nil, errNoChapters
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	var chapters []Chapter
+	for _, edition := range ebmlChildren(data) {
+		if edition.id != mkvEditionEntry {
+			continue
+		}
+		for _, atom := range ebmlChildren(edition.data) {
+			if atom.id != mkvChapterAtom {
+				continue
+			}
+			var c Chapter
+			hidden := false
+			for _, e := range ebmlChildren(atom.data) {
+				switch e.id {
+				case mkvChapterTimeStart:
+					c.Start = int64(ebmlUint(e.data) / 1000000)
+				case mkvChapterHidden:
+					hidden = ebmlUint(e.data) == 1
+				case mkvChapterDisplay:
+					for _, d := range ebmlChildren(e.data) {
+						if d.id == mkvChapString && c.Title == "" {
+							c.Title = string(d.data)
+						}
+					}
+				}
+			}
+			if !hidden {
+				chapters = append(chapters, c)
+			}
+		}
+		// only the first (default) edition is used
+		break
+	}
+
+	if len(chapters) == 0 {
+		return nil, errNoChapters
+	}
+	return chapters, nil
+}
+
+// reads the nero style chapters (moov/udta/chpl) of a mp4 file
+func readMP4Chapters(f io.ReadSeeker) ([]Chapter, error) {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return nil, errNoChapters
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		headerSize := int64(8)
+		if size == 1 {
+			var ext [8]byte
+			if _, err := io.ReadFull(f, ext[:]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(ext[:]))
+			headerSize = 16
+		}
+		if size != 0 && size < headerSize {
+			return nil, errors.New("invalid mp4 atom")
+		}
+		if string(hdr[4:]) == "moov" {
+			if size == 0 || size-headerSize > maxChapterData {
+				return nil, errNoChapters
+			}
+			moov := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(f, moov); err != nil {
+				return nil, err
+			}
+			udta := mp4Atom(moov, "udta")
+			if udta == nil {
+				return nil, errNoChapters
+			}
+			return parseChpl(mp4Atom(udta, "chpl"))
+		}
+		if size == 0 {
+			return nil, errNoChapters
+		}
+		if _, err := f.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// returns the content of the first child atom of type typ
+func mp4Atom(b []byte, typ string) []byte {
+	for len(b) >= 8 {
+		size := int(binary.BigEndian.Uint32(b[:4]))
+		if size < 8 || size > len(b) {
+			return nil
+		}
+		if string(b[4:8]) == typ {
+			return b[8:size]
+		}
+		b = b[size:]
+	}
+	return nil
+}
+
+// parses the content of a chpl atom
+func parseChpl(b []byte) ([]Chapter, error) {
+	if len(b) < 5 {
+		return nil, errNoChapters
+	}
+	version := b[0]
+	b = b[4:]
+	if version > 0 {
+		if len(b) < 5 {
+			return nil, errNoChapters
+		}
+		b = b[4:]
+	}
+	count := int(b[0])
+	b = b[1:]
+
+	var chapters []Chapter
+	for i := 0; i < count && len(b) >= 9; i++ {
+		// start is stored in units of 100ns
+		start := int64(binary.BigEndian.Uint64(b[:8]) / 10000)
+		l := int(b[8])
+		b = b[9:]
+		if l > len(b) {
+			break
+		}
+		chapters = append(chapters, Chapter{Title: string(b[:l]), Start: start})
+		b = b[l:]
+	}
+
+	if len(chapters) == 0 {
+		return nil, errNoChapters
+	}
+	return chapters, nil
+}
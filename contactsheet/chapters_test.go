@@ -0,0 +1,248 @@
+package contactsheet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// encodes an ebml element, sizes always use 8 bytes
+func ebml(id uint64, children ...[]byte) []byte {
+	var b bytes.Buffer
+	idBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(idBytes, id)
+	for len(idBytes) > 1 && idBytes[0] == 0 {
+		idBytes = idBytes[1:]
+	}
+	b.Write(idBytes)
+	data := bytes.Join(children, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(data)))
+	size[0] = 0x01
+	b.Write(size)
+	b.Write(data)
+	return b.Bytes()
+}
+
+// an element header claiming size bytes of data which aren't there
+func ebmlHeader(id uint64, size uint64) []byte {
+	b := ebml(id)
+	binary.BigEndian.PutUint64(b[len(b)-8:], size)
+	b[len(b)-8] = 0x01
+	return b
+}
+
+func ebmlUintData(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func mkvAtom(ms int64, title string, hidden bool) []byte {
+	children := [][]byte{
+		ebml(mkvChapterTimeStart, ebmlUintData(uint64(ms)*1000000)),
+		ebml(mkvChapterDisplay, ebml(mkvChapString, []byte(title))),
+	}
+	if hidden {
+		children = append(children, ebml(mkvChapterHidden, []byte{1}))
+	}
+	return ebml(mkvChapterAtom, children...)
+}
+
+func mkvFile(segment ...[]byte) []byte {
+	return append(ebml(0x1A45DFA3, []byte("matroska")), ebml(mkvSegment, segment...)...)
+}
+
+func mp4Box(typ string, children ...[]byte) []byte {
+	data := bytes.Join(children, nil)
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(b, uint32(8+len(data)))
+	copy(b[4:], typ)
+	return append(b, data...)
+}
+
+func chpl(version byte, count int, chapters ...Chapter) []byte {
+	b := []byte{version, 0, 0, 0}
+	if version > 0 {
+		b = append(b, 0, 0, 0, 0)
+	}
+	b = append(b, byte(count))
+	for _, c := range chapters {
+		b = append(b, ebmlUintData(uint64(c.Start)*10000)...)
+		b = append(b, byte(len(c.Title)))
+		b = append(b, c.Title...)
+	}
+	return mp4Box("chpl", b)
+}
+
+func mp4File(moov ...[]byte) []byte {
+	return append(mp4Box("ftyp", []byte("isom")), mp4Box("moov", moov...)...)
+}
+
+func TestReadMatroskaChapters(t *testing.T) {
+	chapters := ebml(mkvChapters, ebml(mkvEditionEntry,
+		mkvAtom(0, "Intro", false),
+		mkvAtom(90000, "Hidden", true),
+		mkvAtom(120000, "Main", false),
+	))
+	cluster := ebml(mkvCluster, []byte{0, 0, 0, 0})
+	// the seek position is relative to the data of the segment
+	seekHead := func(pos int) []byte {
+		return ebml(mkvSeekHead, ebml(mkvSeek,
+			ebml(mkvSeekID, ebmlUintData(mkvChapters)),
+			ebml(mkvSeekPosition, ebmlUintData(uint64(pos))),
+		))
+	}
+	headSize := len(seekHead(0))
+	valid := mkvFile(chapters)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Chapter
+		wantErr bool
+	}{
+		{"chapters before the clusters", valid, []Chapter{{Title: "Intro"}, {Title: "Main", Start: 120000}}, false},
+		{"chapters found by the seek head", mkvFile(seekHead(headSize+len(cluster)), cluster, chapters), []Chapter{{Title: "Intro"}, {Title: "Main", Start: 120000}}, false},
+		{"first edition only", mkvFile(ebml(mkvChapters, ebml(mkvEditionEntry, mkvAtom(1000, "A", false)), ebml(mkvEditionEntry, mkvAtom(2000, "B", false)))), []Chapter{{Title: "A", Start: 1000}}, false},
+		{"no chapters", mkvFile(cluster), nil, true},
+		{"only hidden chapters", mkvFile(ebml(mkvChapters, ebml(mkvEditionEntry, mkvAtom(0, "Hidden", true)))), nil, true},
+		{"wrong seek position", mkvFile(seekHead(1), cluster, chapters), nil, true},
+		{"truncated", valid[:len(valid)-10], nil, true},
+		{"truncated header", valid[:5], nil, true},
+		{"empty", nil, nil, true},
+		{"oversized chapters", mkvFile(ebmlHeader(mkvChapters, 1<<40)), nil, true},
+		{"oversized seek head", mkvFile(ebmlHeader(mkvSeekHead, maxChapterData+1)), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMatroskaChapters(bytes.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMP4Chapters(t *testing.T) {
+	two := []Chapter{{Title: "Intro"}, {Title: "Main", Start: 120000}}
+	valid := mp4File(mp4Box("udta", chpl(0, 2, two...)))
+
+	// a moov atom with a 64 bit size larger than maxChapterData
+	huge := mp4Box("ftyp", []byte("isom"))
+	huge = append(huge, 0, 0, 0, 1, 'm', 'o', 'o', 'v')
+	huge = append(huge, ebmlUintData(maxChapterData+17)...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Chapter
+		wantErr bool
+	}{
+		{"version 0", valid, two, false},
+		{"version 1", mp4File(mp4Box("udta", chpl(1, 2, two...))), two, false},
+		{"other atoms around", mp4File(mp4Box("mvhd", make([]byte, 100)), mp4Box("udta", mp4Box("meta"), chpl(0, 2, two...))), two, false},
+		{"count larger than entries", mp4File(mp4Box("udta", chpl(0, 5, two...))), two, false},
+		{"no udta", mp4File(mp4Box("mvhd", make([]byte, 100))), nil, true},
+		{"no chpl", mp4File(mp4Box("udta", mp4Box("meta"))), nil, true},
+		{"empty chpl", mp4File(mp4Box("udta", chpl(0, 0))), nil, true},
+		{"no moov", mp4Box("ftyp", []byte("isom")), nil, true},
+		{"truncated", valid[:len(valid)-10], nil, true},
+		{"atom smaller than its header", append(mp4Box("ftyp"), 0, 0, 0, 4, 'm', 'o', 'o', 'v'), nil, true},
+		{"oversized moov", huge, nil, true},
+		{"empty", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMP4Chapters(bytes.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChpl(t *testing.T) {
+	two := []Chapter{{Title: "Intro"}, {Title: "Main", Start: 120000}}
+	// the content of the atom without its header
+	content := func(version byte, count int, chapters ...Chapter) []byte {
+		return chpl(version, count, chapters...)[8:]
+	}
+	valid := content(0, 2, two...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Chapter
+		wantErr bool
+	}{
+		{"valid", valid, two, false},
+		{"truncated title", valid[:len(valid)-2], two[:1], false},
+		{"truncated start", valid[:len(valid)-10], two[:1], false},
+		{"title longer than the atom", append(content(0, 1), 0, 0, 0, 0, 0, 0, 0, 0, 255, 'a'), nil, true},
+		{"only the header", valid[:5], nil, true},
+		{"header too short", valid[:4], nil, true},
+		{"version 1 header too short", content(1, 2)[:6], nil, true},
+		{"nil", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChpl(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadChaptersSorted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := []Chapter{
+		{Title: "One", Start: 0, End: 60000},
+		{Title: "Two", Start: 60000, End: 120000},
+		{Title: "Three", Start: 120000, End: 300000},
+	}
+	files := map[string][]byte{
+		"movie.mkv": mkvFile(ebml(mkvChapters, ebml(mkvEditionEntry,
+			mkvAtom(120000, "Three", false),
+			mkvAtom(0, "One", false),
+			mkvAtom(60000, "Two", false),
+		))),
+		"movie.mp4": mp4File(mp4Box("udta", chpl(0, 3, want[2], want[0], want[1]))),
+	}
+	for name, data := range files {
+		fn := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fn, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readChapters(fn, 300000)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, err := readChapters(filepath.Join(dir, "movie.avi"), 300000); err == nil {
+		t.Error("expected an error for an unsupported container")
+	}
+}
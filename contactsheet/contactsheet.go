@@ -0,0 +1,244 @@
+// Package contactsheet generates contact sheets (a grid of screenshots with
+// an optional header) from video files.
+//
+// It contains the core of the mt command and can be embedded into other
+// programs:
+//
+//	opts := contactsheet.DefaultOptions()
+//	opts.Numcaps = 9
+//	opts.Columns = 3
+//	res, err := contactsheet.Generate(ctx, "movie.mkv", opts)
+//	if err != nil {
+//		return err
+//	}
+//	imaging.Save(res.Sheet, "movie.jpg")
+package contactsheet
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/BurntSushi/freetype-go/freetype"
+	"github.com/BurntSushi/freetype-go/freetype/truetype"
+	log "github.com/sirupsen/logrus"
+)
+
+// Frame is a single screenshot of the video
+type Frame struct {
+	Image     image.Image     // the screenshot with filters, timestamps and watermarks applied
+	Timestamp int64           // position in the video in milliseconds
+	Start     int64           // start of the part of the video the screenshot stands for, the previous screenshot or the start of its range
+	Chapter   string          // title of the chapter in chapter mode
+	Bounds    image.Rectangle // position of the screenshot on the contact sheet
+	Censored  bool            // the screenshot was flagged by the nudity detection and censored, see Options.Censor
+}
+
+// Result is returned by Generate
+type Result struct {
+	Media  *Media      // informations about the video
+	Sheet  image.Image // the contact sheet, nil if Options.SingleImages is set
+	Frames []Frame     // all screenshots in order of their timestamps
+
+	// Rejections counts the frames every rejector rejected, by name
+	Rejections map[string]int
+}
+
+// WebVTT returns the content of a .vtt file mapping the video timeline to
+// the screenshots of the contact sheet saved as imgName, censored
+// screenshots are preceded by a "NOTE censored" comment
+func (r *Result) WebVTT(imgName string) string {
+	vttContent := "WEBVTT\n"
+	for _, f := range r.Frames {
+		if f.Censored {
+			vttContent += "\nNOTE censored\n"
+		}
+		vttContent = fmt.Sprintf("%s\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", vttContent, formatTimestampMS(f.Start), formatTimestampMS(f.Timestamp), imgName, f.Bounds.Min.X, f.Bounds.Min.Y, f.Bounds.Dx(), f.Bounds.Dy())
+	}
+	return vttContent
+}
+
+// generator holds the state of a single Generate call
+type generator struct {
+	opts    Options
+	log     log.FieldLogger
+	fn      string
+	media   *Media
+	titles  []string // chapter title of every screenshot in chapter mode
+	font    *truetype.Font
+	columns int
+	numcaps int
+	start   int64       // start of the range screenshots are taken from
+	end     int64       // end of the range screenshots are taken from, retries stay before it
+	ranges  []timeRange // parts of the video screenshots are taken from, see Options.Ranges
+	seed    int64       // seed of all random decisions
+
+	featured     map[int]bool // screenshots enlarged by the hero layout
+	featuredBest int          // number of featured screenshots still to be picked by quality
+	tile         image.Point  // size of a regular screenshot on the sheet
+
+	rejectors  []FrameRejector // see Options.Reject
+	rejectedMu sync.Mutex
+	rejected   map[string]int // number of frames rejected by every rejector
+}
+
+// Generate takes screenshots of the video fn and composes them into a
+// contact sheet as configured by opts. Panics while decoding or processing
+// the video are recovered and returned as error.
+func Generate(ctx context.Context, fn string, opts Options) (res *Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = nil
+			err = fmt.Errorf("panic while processing %s: %v", fn, r)
+		}
+	}()
+
+	g, err := newGenerator(fn, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fontBytes, err := g.getFont(opts.Font)
+	if err != nil {
+		g.log.Warn("unable to load font, disableing timestamps and header")
+	} else if g.font, err = freetype.ParseFont(fontBytes); err != nil {
+		g.log.Errorf("freetype parse error: %v", err)
+	}
+
+	g.media, err = g.probe()
+	if err != nil {
+		return nil, err
+	}
+	defer g.media.close()
+
+	frames, err := g.generateScreenshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.logRejections()
+
+	res = &Result{Media: g.media, Frames: frames, Rejections: g.rejected}
+	if len(frames) > 0 && !opts.SingleImages {
+		res.Sheet, err = g.makeContactSheet(res.Frames)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// creates a generator for the video fn and validates opts
+func newGenerator(fn string, opts Options) (*generator, error) {
+	g := &generator{
+		opts:     opts,
+		log:      opts.Logger,
+		fn:       fn,
+		columns:  opts.Columns,
+		numcaps:  opts.Numcaps,
+		rejected: map[string]int{},
+	}
+	if g.log == nil {
+		g.log = log.StandardLogger()
+	}
+	if g.seed = opts.Seed; g.seed == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(fn))
+		g.seed = int64(h.Sum64())
+	}
+
+	switch opts.TimestampFormat {
+	case "", "hms", "hms.ms", "smpte", "seconds", "percent":
+	default:
+		return nil, fmt.Errorf("unknown timestamp format: %s", opts.TimestampFormat)
+	}
+	switch opts.RetryDirection {
+	case "", "forward", "backward", "alternate":
+	default:
+		return nil, fmt.Errorf("unknown retry direction: %s", opts.RetryDirection)
+	}
+	switch opts.RetryFallback {
+	case "", "last", "best":
+	default:
+		return nil, fmt.Errorf("unknown retry fallback: %s", opts.RetryFallback)
+	}
+	switch opts.Layout {
+	case "", "grid", "hero":
+	default:
+		return nil, fmt.Errorf("unknown layout: %s", opts.Layout)
+	}
+	switch opts.HeroSize {
+	case "", "2x2", "row":
+	default:
+		return nil, fmt.Errorf("unknown hero size: %s", opts.HeroSize)
+	}
+	for _, f := range opts.Featured {
+		if !validFeatured(f) {
+			return nil, fmt.Errorf("invalid featured screenshot: %s, use first, middle, last, best or a number", f)
+		}
+	}
+	if _, err := parseAspect(opts.SheetAspect); err != nil {
+		return nil, err
+	}
+	switch opts.Censor {
+	case "", "blur", "pixelate":
+	default:
+		return nil, fmt.Errorf("unknown censor mode: %s", opts.Censor)
+	}
+	switch opts.CensorArea {
+	case "", "frame", "skin":
+	default:
+		return nil, fmt.Errorf("unknown censor area: %s", opts.CensorArea)
+	}
+	if step, err := parseDuration(opts.RetryStep, 100); opts.RetryStep != "" && (err != nil || step <= 0) {
+		return nil, fmt.Errorf("retry step has to be a positive time value: %s", opts.RetryStep)
+	}
+	if step, err := parseDuration(opts.CandidateStep, 100); opts.CandidateStep != "" && (err != nil || step <= 0) {
+		return nil, fmt.Errorf("candidate step has to be a positive time value: %s", opts.CandidateStep)
+	}
+	var err error
+	if g.rejectors, err = g.newRejectors(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// formats a timestamp in milliseconds as HH:MM:SS
+func formatTimestamp(ms int64) string {
+	s := ms / 1000
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// formats a timestamp in milliseconds as HH:MM:SS.mmm
+func formatTimestampMS(ms int64) string {
+	return fmt.Sprintf("%s.%03d", formatTimestamp(ms), ms%1000)
+}
+
+// formats the label drawn onto a screenshot taken at ms as configured by
+// Options.TimestampFormat
+func (g *generator) formatLabel(ms int64) string {
+	switch g.opts.TimestampFormat {
+	case "hms.ms":
+		return formatTimestampMS(ms)
+	case "smpte":
+		// non drop frame timecode, the frame number is based on the nominal frame rate
+		fps := math.Round(g.media.FPS)
+		frame := int64(0)
+		if fps > 0 {
+			frame = ms % 1000 * int64(fps) / 1000
+		}
+		return fmt.Sprintf("%s:%02d", formatTimestamp(ms), frame)
+	case "seconds":
+		return fmt.Sprintf("%d.%03ds", ms/1000, ms%1000)
+	case "percent":
+		if g.media.Duration <= 0 {
+			return formatTimestamp(ms)
+		}
+		return fmt.Sprintf("%.1f%%", float64(ms)*100/float64(g.media.Duration))
+	}
+	return formatTimestamp(ms)
+}
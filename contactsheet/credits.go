@@ -0,0 +1,177 @@
+package contactsheet
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"gitlab.com/opennota/screengen"
+)
+
+const (
+	creditsSearch        = 0.2        // part of the video searched for intro or credits at each end
+	creditsMaxSearch     = 15 * 60000 // but never more than 15 minutes
+	creditsSamples       = 60         // frames sampled in the searched part
+	creditsSampleWidth   = 160        // width of the sampled frames
+	creditsMinRun        = 3          // minimum number of credit frames in a row
+	creditsEdgeSamples   = 3          // the run has to start or end within this many samples of the searched part
+	creditsDarkLuma      = 48         // pixels below this luma count as background
+	creditsBrightLuma    = 200        // pixels above this luma count as text
+	creditsMinDark       = 0.6        // minimum part of background pixels
+	creditsMaxMidtones   = 0.15       // maximum part of pixels neither background nor text
+	creditsMaxSaturation = 40         // maximum mean saturation (0-255)
+)
+
+// detects the intro at the start of the video and returns the timestamp
+// the actual content starts at, 0 if no intro was found
+func (g *generator) detectIntro(ctx context.Context, pool *decoderPool, length int64) (int64, error) {
+	region := creditsRegion(length)
+	stamps, credits, err := g.scanCredits(ctx, pool, 0, region)
+	if err != nil {
+		return 0, err
+	}
+	first, last := leadingCreditsRun(credits)
+	if last-first+1 < creditsMinRun {
+		g.log.Infof("no intro detected in the first %s", formatTimestamp(region))
+		return 0, nil
+	}
+	if last == len(stamps)-1 {
+		// the intro lasts at least until the end of the searched part
+		return region, nil
+	}
+	return stamps[last+1], nil
+}
+
+// detects the credits at the end of the video and returns the timestamp
+// they start at, length if no credits were found
+func (g *generator) detectCredits(ctx context.Context, pool *decoderPool, length int64) (int64, error) {
+	region := creditsRegion(length)
+	stamps, credits, err := g.scanCredits(ctx, pool, length-region, length)
+	if err != nil {
+		return 0, err
+	}
+	first, last := trailingCreditsRun(credits)
+	if last-first+1 < creditsMinRun {
+		g.log.Infof("no credits detected in the last %s", formatTimestamp(region))
+		return length, nil
+	}
+	if first == 0 {
+		return length - region, nil
+	}
+	return stamps[first], nil
+}
+
+// returns the length of the part searched at each end of the video
+func creditsRegion(length int64) int64 {
+	region := int64(float64(length) * creditsSearch)
+	if region > creditsMaxSearch {
+		region = creditsMaxSearch
+	}
+	return region
+}
+
+// samples frames between start and stop and reports for every sample if it
+// looks like part of the credits, nothing is sampled if the clip is too short
+func (g *generator) scanCredits(ctx context.Context, pool *decoderPool, start, stop int64) ([]int64, []bool, error) {
+	step := (stop - start) / creditsSamples
+	if step <= 0 {
+		g.log.Warn("clip is too short to detect intro or credits")
+		return nil, nil, nil
+	}
+
+	g.log.Debugf("scanning %s to %s for intro or credits", formatTimestamp(start), formatTimestamp(stop))
+	stamps := make([]int64, creditsSamples)
+	credits := make([]bool, creditsSamples)
+	err := g.parallel(ctx, pool.get(creditsSamples), creditsSamples, func(gen *screengen.Generator, i int) error {
+		stamps[i] = start + int64(i)*step + step/2
+		w, h := creditsSampleWidth, creditsSampleWidth
+		if g.media.Width > 0 && g.media.Height > 0 {
+			h = creditsSampleWidth * g.media.Height / g.media.Width
+		}
+		if h < 1 {
+			h = 1
+		}
+		img, err := gen.ImageWxH(stamps[i], w, h)
+		if err != nil {
+			return fmt.Errorf("can't sample frame at %s: %v", formatTimestamp(stamps[i]), err)
+		}
+		credits[i] = isCreditsFrame(g.cropFrame(img))
+		return nil
+	})
+	return stamps, credits, err
+}
+
+// returns the first and last index of the run of credit frames at the
+// start of the samples, -1, -1 if there is none. The run has to start within
+// the first creditsEdgeSamples samples, a single other frame (like a title
+// card) doesn't end it.
+func leadingCreditsRun(credits []bool) (int, int) {
+	first, last := -1, -1
+	for i, c := range credits {
+		if !c {
+			if last >= 0 && i-last > 1 {
+				break
+			}
+			continue
+		}
+		if first < 0 {
+			if i >= creditsEdgeSamples {
+				break
+			}
+			first = i
+		}
+		last = i
+	}
+	return first, last
+}
+
+// returns the first and last index of the run of credit frames at the end
+// of the samples, see leadingCreditsRun
+func trailingCreditsRun(credits []bool) (int, int) {
+	n := len(credits)
+	reversed := make([]bool, n)
+	for i, c := range credits {
+		reversed[n-1-i] = c
+	}
+	first, last := leadingCreditsRun(reversed)
+	if last < 0 {
+		return -1, -1
+	}
+	return n - 1 - last, n - 1 - first
+}
+
+// decides if a frame looks like credits, a logo card or a black frame: a
+// mostly dark and colorless background with some bright text but hardly
+// any midtones
+func isCreditsFrame(img image.Image) bool {
+	nrgba := imaging.Clone(img)
+	var dark, mid, saturation, pixels int
+	for i := 0; i+3 < len(nrgba.Pix); i += 4 {
+		r, g, b := int(nrgba.Pix[i]), int(nrgba.Pix[i+1]), int(nrgba.Pix[i+2])
+		l := (299*r + 587*g + 114*b) / 1000
+		switch {
+		case l < creditsDarkLuma:
+			dark++
+		case l <= creditsBrightLuma:
+			mid++
+		}
+		max, min := r, r
+		for _, c := range []int{g, b} {
+			if c > max {
+				max = c
+			}
+			if c < min {
+				min = c
+			}
+		}
+		saturation += max - min
+		pixels++
+	}
+	if pixels == 0 {
+		return false
+	}
+	return float64(dark)/float64(pixels) >= creditsMinDark &&
+		float64(mid)/float64(pixels) <= creditsMaxMidtones &&
+		saturation/pixels <= creditsMaxSaturation
+}
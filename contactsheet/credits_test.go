@@ -0,0 +1,108 @@
+package contactsheet
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// parses samples like "xx.x..", x is a credits frame
+func creditSamples(s string) []bool {
+	credits := make([]bool, len(s))
+	for i, c := range s {
+		credits[i] = c == 'x'
+	}
+	return credits
+}
+
+func TestLeadingCreditsRun(t *testing.T) {
+	tests := []struct {
+		samples     string
+		first, last int
+	}{
+		{"xxxx......", 0, 3},
+		{"..xxxx....", 2, 5},
+		// a title card doesn't end the intro
+		{"xxx.xx....", 0, 5},
+		{"xx..xxxxxx", 0, 1},
+		{"xxxxxxxxxx", 0, 9},
+		// a dark scene later on is no intro
+		{"...xxxxx..", -1, -1},
+		{".........x", -1, -1},
+		{"..........", -1, -1},
+		{"", -1, -1},
+	}
+	for _, tt := range tests {
+		first, last := leadingCreditsRun(creditSamples(tt.samples))
+		if first != tt.first || last != tt.last {
+			t.Errorf("leadingCreditsRun(%q) = %d, %d, want %d, %d", tt.samples, first, last, tt.first, tt.last)
+		}
+	}
+}
+
+func TestTrailingCreditsRun(t *testing.T) {
+	tests := []struct {
+		samples     string
+		first, last int
+	}{
+		{"......xxxx", 6, 9},
+		{"....xxxx..", 4, 7},
+		{"....xx.xxx", 4, 9},
+		{"xxxxxx..xx", 8, 9},
+		{"xxxxxxxxxx", 0, 9},
+		// a dark scene before the end are no credits
+		{"..xxxxx...", -1, -1},
+		{"x.........", -1, -1},
+		{"..........", -1, -1},
+		{"", -1, -1},
+	}
+	for _, tt := range tests {
+		first, last := trailingCreditsRun(creditSamples(tt.samples))
+		if first != tt.first || last != tt.last {
+			t.Errorf("trailingCreditsRun(%q) = %d, %d, want %d, %d", tt.samples, first, last, tt.first, tt.last)
+		}
+	}
+}
+
+func TestIsCreditsFrame(t *testing.T) {
+	solid := func(c color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 160, 90))
+		draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+		return img
+	}
+	black := color.RGBA{0, 0, 0, 255}
+
+	// white lines of text on black
+	text := solid(black).(*image.RGBA)
+	for y := 10; y < 90; y += 20 {
+		draw.Draw(text, image.Rect(40, y, 120, y+5), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+
+	// a scene with all shades of grey
+	gradient := image.NewGray(image.Rect(0, 0, 256, 10))
+	for x := 0; x < 256; x++ {
+		for y := 0; y < 10; y++ {
+			gradient.SetGray(x, y, color.Gray{uint8(x)})
+		}
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want bool
+	}{
+		{"black", solid(black), true},
+		{"text", text, true},
+		{"gradient", gradient, false},
+		{"grey", solid(color.Gray{128}), false},
+		{"white", solid(color.White), false},
+		{"dark blue night scene", solid(color.RGBA{0, 0, 120, 255}), false},
+		{"empty", image.NewRGBA(image.Rect(0, 0, 0, 0)), false},
+	}
+	for _, tt := range tests {
+		if got := isCreditsFrame(tt.img); got != tt.want {
+			t.Errorf("%s: isCreditsFrame = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package contactsheet
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"gitlab.com/opennota/screengen"
+)
+
+const (
+	cropSamples  = 10 // frames sampled to detect black bars
+	cropMeanLuma = 24 // maximum mean luma of a border row or column
+	cropMaxLuma  = 64 // maximum luma of any pixel in a border row or column
+	cropMinBar   = 4  // bars smaller than this (in px) are not cropped
+)
+
+// detects black bars (letterbox and pillarbox) by sampling frames spread
+// over the video and returns the active area of the video. The bars have
+// to be found in every sample, frames which are dark as a whole are ignored.
+func (g *generator) detectCrop(ctx context.Context, pool *decoderPool) (image.Rectangle, error) {
+	full := image.Rect(0, 0, g.media.Width, g.media.Height)
+	length := 1000 * (g.media.Duration / 1000)
+	if full.Empty() || length <= 0 {
+		return full, nil
+	}
+
+	areas := make([]image.Rectangle, cropSamples)
+	err := g.parallel(ctx, pool.get(cropSamples), cropSamples, func(gen *screengen.Generator, i int) error {
+		ts := length * int64(2*i+1) / (2 * cropSamples)
+		img, err := gen.Image(ts)
+		if err != nil {
+			return fmt.Errorf("can't sample frame at %s: %v", formatTimestamp(ts), err)
+		}
+		areas[i] = activeArea(img)
+		return nil
+	})
+	if err != nil {
+		return full, err
+	}
+
+	// the union of all samples is the largest area with content
+	var crop image.Rectangle
+	for _, a := range areas {
+		crop = crop.Union(a)
+	}
+	if crop.Empty() {
+		g.log.Debug("all sampled frames are dark, not cropping")
+		return full, nil
+	}
+	crop = crop.Intersect(full)
+
+	// ignore tiny bars and keep the size even for the decoder
+	if crop.Min.X < cropMinBar {
+		crop.Min.X = 0
+	}
+	if crop.Min.Y < cropMinBar {
+		crop.Min.Y = 0
+	}
+	if full.Max.X-crop.Max.X < cropMinBar {
+		crop.Max.X = full.Max.X
+	}
+	if full.Max.Y-crop.Max.Y < cropMinBar {
+		crop.Max.Y = full.Max.Y
+	}
+	crop.Min.X += crop.Min.X % 2
+	crop.Min.Y += crop.Min.Y % 2
+	crop.Max.X -= crop.Dx() % 2
+	crop.Max.Y -= crop.Dy() % 2
+
+	if crop != full {
+		g.log.Infof("black bars detected, cropping to %dx%d at %d,%d", crop.Dx(), crop.Dy(), crop.Min.X, crop.Min.Y)
+	}
+	return crop, nil
+}
+
+// returns the area of img inside dark borders, an empty rectangle if the
+// whole image is dark
+func activeArea(img image.Image) image.Rectangle {
+	nrgba := imaging.Clone(img)
+	w, h := nrgba.Bounds().Dx(), nrgba.Bounds().Dy()
+	luma := make([]uint8, w*h)
+	for i := range luma {
+		r, g, b := int(nrgba.Pix[4*i]), int(nrgba.Pix[4*i+1]), int(nrgba.Pix[4*i+2])
+		luma[i] = uint8((299*r + 587*g + 114*b) / 1000)
+	}
+
+	// dark reports if the n pixels starting at luma[start] with the given
+	// stride belong to a border
+	dark := func(start, stride, n int) bool {
+		sum := 0
+		for i := 0; i < n; i++ {
+			l := int(luma[start+i*stride])
+			if l > cropMaxLuma {
+				return false
+			}
+			sum += l
+		}
+		return sum < cropMeanLuma*n
+	}
+
+	top, bottom, left, right := 0, h, 0, w
+	for top < h && dark(top*w, 1, w) {
+		top++
+	}
+	if top == h {
+		return image.Rectangle{}
+	}
+	for bottom > top && dark((bottom-1)*w, 1, w) {
+		bottom--
+	}
+	for left < w && dark(top*w+left, w, bottom-top) {
+		left++
+	}
+	for right > left && dark(top*w+right-1, w, bottom-top) {
+		right--
+	}
+	return image.Rect(left, top, right, bottom)
+}
+
+// crops a decoded frame to the detected active area, frames decoded at a
+// smaller size get a scaled crop
+func (g *generator) cropFrame(img image.Image) image.Image {
+	crop := g.media.Crop
+	full := image.Rect(0, 0, g.media.Width, g.media.Height)
+	if crop.Empty() || crop == full {
+		return img
+	}
+	b := img.Bounds()
+	if b.Dx() != full.Dx() || b.Dy() != full.Dy() {
+		crop = image.Rect(
+			crop.Min.X*b.Dx()/full.Dx(), crop.Min.Y*b.Dy()/full.Dy(),
+			crop.Max.X*b.Dx()/full.Dx(), crop.Max.Y*b.Dy()/full.Dy(),
+		)
+	}
+	return imaging.Crop(img, crop.Add(b.Min))
+}
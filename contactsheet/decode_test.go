@@ -0,0 +1,51 @@
+package contactsheet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"gitlab.com/opennota/screengen"
+)
+
+// compares decoding at full resolution with letting the decoder scale the
+// frames (Options.FastScale), run it with a 4K video:
+//
+//	MT_BENCH_VIDEO=movie.mkv go test -run ^$ -bench Decode ./contactsheet
+func BenchmarkDecode(b *testing.B) {
+	fn := os.Getenv("MT_BENCH_VIDEO")
+	if fn == "" {
+		b.Skip("set MT_BENCH_VIDEO to a video file")
+	}
+	const width = 400
+
+	gen, err := screengen.NewGenerator(fn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer gen.Close()
+	ts := gen.Duration / 2
+
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			img, err := gen.Image(ts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			imaging.Resize(img, width, 0, imaging.Lanczos)
+		}
+	})
+
+	b.Run("fast", func(b *testing.B) {
+		g := &generator{media: &Media{Width: gen.Width(), Height: gen.Height()}}
+		g.opts.Width = width
+		w, h := g.decodeSize(false)
+		for i := 0; i < b.N; i++ {
+			img, err := gen.ImageWxH(ts, w, h)
+			if err != nil {
+				b.Fatal(err)
+			}
+			imaging.Resize(img, width, 0, imaging.Lanczos)
+		}
+	})
+}
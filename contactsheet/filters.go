@@ -1,4 +1,4 @@
-package main
+package contactsheet
 
 import (
 	"bytes"
@@ -6,6 +6,8 @@ import (
 	"image"
 	"image/color"
 	"math"
+
+	"github.com/mutschler/mt/contactsheet/internal/assets"
 )
 
 func clamp(v float64) uint8 {
@@ -16,9 +18,9 @@ func sigmoid(a, b, x float64) float64 {
 	return 1 / (1 + math.Exp(b*(a-x)))
 }
 
-// ImageStrip look
-func ImageStripFilter(img image.Image) image.Image {
-	l, _ := Asset("strip_left.jpg")
+// imageStrip look
+func imageStripFilter(img image.Image) image.Image {
+	l, _ := assets.Asset("strip_left.jpg")
 	lr := bytes.NewReader(l)
 	strip, _ := imaging.Decode(lr)
 	strip = imaging.Resize(strip, 0, img.Bounds().Dy(), imaging.Lanczos)
@@ -27,7 +29,7 @@ func ImageStripFilter(img image.Image) image.Image {
 	dst = imaging.Paste(dst, img, image.Pt(strip.Bounds().Dx(), 0))
 	dst = imaging.Paste(dst, strip, image.Pt(0, 0))
 
-	r, _ := Asset("strip_right.jpg")
+	r, _ := assets.Asset("strip_right.jpg")
 	rr := bytes.NewReader(r)
 	stripr, _ := imaging.Decode(rr)
 	stripr = imaging.Resize(stripr, 0, img.Bounds().Dy(), imaging.Lanczos)
@@ -36,7 +38,7 @@ func ImageStripFilter(img image.Image) image.Image {
 }
 
 // sigmoid function to simulate image cross processing, best results with midpoint: 0.5 and factor 10
-func CrossProcessingFilter(img image.Image, midpoint, factor float64) *image.NRGBA {
+func crossProcessingFilter(img image.Image, midpoint, factor float64) *image.NRGBA {
 
 	red := make([]uint8, 256)
 	green := make([]uint8, 256)
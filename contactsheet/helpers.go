@@ -0,0 +1,330 @@
+package contactsheet
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/mutschler/mt/contactsheet/internal/assets"
+)
+
+// returns a random float32 between min and max
+func randomInt(r *rand.Rand, min, max int) float32 {
+	if max <= min {
+		return float32(min)
+	}
+	return float32(r.Intn(max-min+1) + min)
+}
+
+// returns the random source for the i-th screenshot, it only depends on
+// the seed so every run creates the same output
+func (g *generator) random(i int) *rand.Rand {
+	return rand.New(rand.NewSource(g.seed + int64(i)))
+}
+
+// width images are scaled to before measuring their sharpness, this keeps
+// the metric independent of the video resolution and the screenshot width
+const sharpnessWidth = 512
+
+// luma range below which an image has no detail at all
+const sharpnessMinRange = 16
+
+// returns the sharpness of img as variance of the laplacian of its luma.
+// The contrast is stretched first so dark frames aren't considered blurry,
+// images without any contrast have a sharpness of 0.
+func sharpness(img image.Image) float64 {
+	small := imaging.Resize(img, sharpnessWidth, 0, imaging.Linear)
+	w, h := small.Bounds().Dx(), small.Bounds().Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+
+	luma := make([]float64, w*h)
+	var hist [256]int
+	for i := range luma {
+		r, g, b := float64(small.Pix[4*i]), float64(small.Pix[4*i+1]), float64(small.Pix[4*i+2])
+		luma[i] = 0.299*r + 0.587*g + 0.114*b
+		hist[int(luma[i])]++
+	}
+
+	// stretch the contrast between the 1st and 99th percentile
+	lo, hi := -1, -1
+	count := 0
+	for l, n := range hist {
+		count += n
+		if lo < 0 && count > len(luma)/100 {
+			lo = l
+		}
+		if hi < 0 && count >= len(luma)*99/100 {
+			hi = l
+		}
+	}
+	if hi-lo < sharpnessMinRange {
+		return 0
+	}
+	scale := 255 / float64(hi-lo)
+
+	var sum, sum2 float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			lap := (4*luma[i] - luma[i-1] - luma[i+1] - luma[i-w] - luma[i+w]) * scale
+			sum += lap
+			sum2 += lap * lap
+		}
+	}
+	n := float64((w - 2) * (h - 2))
+	mean := sum / n
+	return sum2/n - mean*mean
+}
+
+const (
+	blankWidth     = 320 // width images are scaled down to before checking if they are blank
+	blankDark      = 50  // pixels with all channels below this are dark
+	blankBright    = 200 // pixels with all channels above this are white
+	blankTolerance = 24  // maximum difference per channel to the dominant color
+)
+
+// returns the percentage of pixels which are either dark or white or have
+// the dominant color of the image, like the blue of a blue screen
+func blankPercent(img image.Image) int {
+	if img.Bounds().Dx() > blankWidth {
+		img = imaging.Resize(img, blankWidth, 0, imaging.Box)
+	}
+	nrgba := imaging.Clone(img)
+	pixels := len(nrgba.Pix) / 4
+	if pixels == 0 {
+		return 0
+	}
+
+	// count dark and white pixels and build a color histogram with 16
+	// levels per channel to find the dominant color
+	var darkOrWhite int
+	var hist [4096]int
+	var sums [4096][3]int
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		r, g, b := nrgba.Pix[i], nrgba.Pix[i+1], nrgba.Pix[i+2]
+		if r < blankDark && g < blankDark && b < blankDark {
+			darkOrWhite++
+		} else if r > blankBright && g > blankBright && b > blankBright {
+			darkOrWhite++
+		}
+		bin := int(r>>4)<<8 | int(g>>4)<<4 | int(b>>4)
+		hist[bin]++
+		sums[bin][0] += int(r)
+		sums[bin][1] += int(g)
+		sums[bin][2] += int(b)
+	}
+
+	mode := 0
+	for bin, n := range hist {
+		if n > hist[mode] {
+			mode = bin
+		}
+	}
+	var dominant [3]int
+	for c := range dominant {
+		dominant[c] = sums[mode][c] / hist[mode]
+	}
+
+	// the dominant color can spread over neighbouring bins
+	uniform := 0
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		near := true
+		for c := 0; c < 3 && near; c++ {
+			d := int(nrgba.Pix[i+c]) - dominant[c]
+			near = d <= blankTolerance && d >= -blankTolerance
+		}
+		if near {
+			uniform++
+		}
+	}
+
+	if uniform > darkOrWhite {
+		return uniform * 100 / pixels
+	}
+	return darkOrWhite * 100 / pixels
+}
+
+// width images are scaled down to before rating their quality
+const qualityWidth = 320
+
+// rates an image from 0 to 1 by its sharpness, brightness, contrast and
+// colorfulness, blank images get a lower rating. Used to pick the best of
+// several candidates.
+func (g *generator) quality(img image.Image) float64 {
+	// saturates at 1, a frame at the default blur threshold gets 0.5
+	sharp := sharpness(img)
+	sharp = sharp / (sharp + 25)
+
+	if img.Bounds().Dx() > qualityWidth {
+		img = imaging.Resize(img, qualityWidth, 0, imaging.Box)
+	}
+	nrgba := imaging.Clone(img)
+
+	// mean and variance of luma and of the opponent color channels
+	var n, sumL, sumL2, sumRG, sumRG2, sumYB, sumYB2 float64
+	for i := 0; i+3 < len(nrgba.Pix); i += 4 {
+		r, g, b := float64(nrgba.Pix[i]), float64(nrgba.Pix[i+1]), float64(nrgba.Pix[i+2])
+		l := 0.299*r + 0.587*g + 0.114*b
+		rg := r - g
+		yb := (r+g)/2 - b
+		sumL += l
+		sumL2 += l * l
+		sumRG += rg
+		sumRG2 += rg * rg
+		sumYB += yb
+		sumYB2 += yb * yb
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	meanL, meanRG, meanYB := sumL/n, sumRG/n, sumYB/n
+	stdL := math.Sqrt(math.Max(sumL2/n-meanL*meanL, 0))
+	stdRG := math.Sqrt(math.Max(sumRG2/n-meanRG*meanRG, 0))
+	stdYB := math.Sqrt(math.Max(sumYB2/n-meanYB*meanYB, 0))
+
+	brightness := 1 - math.Abs(meanL-128)/128
+	contrast := math.Min(stdL/64, 1)
+	// colorfulness metric by Hasler and Suesstrunk, 100 is very colorful
+	colorfulness := math.Min((math.Hypot(stdRG, stdYB)+0.3*math.Hypot(meanRG, meanYB))/100, 1)
+
+	score := 0.4*sharp + 0.2*brightness + 0.2*contrast + 0.2*colorfulness
+	return score * float64(100-blankPercent(img)) / 100
+}
+
+// get font path for fontname
+// searches in common font paths, bindata or absoute path
+func (g *generator) getFont(f string) ([]byte, error) {
+	if !strings.HasSuffix(f, ".ttf") {
+		f = fmt.Sprintf("%s.ttf", f)
+	}
+	if strings.Contains(f, "/") && strings.HasSuffix(f, ".ttf") {
+		if _, err := os.Stat(f); err == nil {
+			g.log.Debugf("using font: %s", f)
+			return ioutil.ReadFile(f)
+		}
+	}
+	fdirs := []string{"/Library/Fonts/", "/usr/share/fonts/", "./"}
+
+	for _, dir := range fdirs {
+		fpath := filepath.Join(dir, f)
+		if _, err := os.Stat(fpath); err == nil {
+			g.log.Debugf("using font: %s", fpath)
+			return ioutil.ReadFile(fpath)
+		}
+	}
+	g.log.Debug("using font: DroidSans.ttf")
+	return assets.Asset("DroidSans.ttf")
+}
+
+// parses a position or length in the video and returns it in milliseconds.
+// s can be a timestamp (hh:mm:ss(.ms) or mm:ss), plain seconds ("90.5"), a
+// Go duration ("1h2m") or a percentage of duration ("5%"). A leading "-"
+// returns a negative value, the caller decides what it is relative to.
+func parseDuration(s string, duration int64) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	v := strings.TrimPrefix(s, "-")
+	ms, err := parsePositiveDuration(v, duration)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: %v", s, err)
+	}
+	if v != s {
+		ms = -ms
+	}
+	return ms, nil
+}
+
+func parsePositiveDuration(s string, duration int64) (int64, error) {
+	switch {
+	case strings.HasSuffix(s, "%"):
+		p := strings.TrimSuffix(s, "%")
+		if !isDecimal(p) {
+			return 0, errors.New("percentage is not a number")
+		}
+		pct, _ := strconv.ParseFloat(p, 64)
+		if pct > 100 {
+			return 0, errors.New("percentage is above 100")
+		}
+		return int64(float64(duration) * pct / 100), nil
+	case strings.Contains(s, ":"):
+		x := strings.Split(s, ":")
+		if len(x) > 3 {
+			return 0, errors.New("not in format hh:mm:ss")
+		}
+		if !isDecimal(x[len(x)-1]) {
+			return 0, errors.New("seconds are not a number")
+		}
+		sec, _ := strconv.ParseFloat(x[len(x)-1], 64)
+		if sec >= 60 {
+			return 0, errors.New("seconds have to be below 60")
+		}
+		var hm int64
+		for i, part := range x[:len(x)-1] {
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+				return 0, errors.New("hours and minutes have to be whole numbers")
+			}
+			if i == len(x)-2 && len(x) == 3 && n >= 60 {
+				return 0, errors.New("minutes have to be below 60")
+			}
+			hm = hm*60 + n
+		}
+		return hm*60000 + int64(math.Round(sec*1000)), nil
+	case isDecimal(s):
+		sec, _ := strconv.ParseFloat(s, 64)
+		return int64(math.Round(sec * 1000)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.New("use hh:mm:ss, seconds, a duration like 1h2m or a percentage like 5%")
+	}
+	return int64(d / time.Millisecond), nil
+}
+
+// reports if s is a plain decimal number like "12" or "12.5"
+func isDecimal(s string) bool {
+	digits := 0
+	dot := false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// returns the difference hash of img, each bit tells if a pixel of the
+// 9x8 greyscale version is brighter than its right neighbour
+func dHash(img image.Image) uint64 {
+	small := imaging.Resize(imaging.Grayscale(img), 9, 8, imaging.Box)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.Pix[small.PixOffset(x, y)] > small.Pix[small.PixOffset(x+1, y)] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
@@ -0,0 +1,238 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	log "github.com/sirupsen/logrus"
+)
+
+// returns a logger which discards everything
+func testLogger() log.FieldLogger {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
+
+// returns a generator for a video with the given duration and resolution
+// which doesn't log anything
+func testGenerator(t *testing.T, opts Options, duration int64, width, height int) *generator {
+	opts.Logger = testLogger()
+	g, err := newGenerator("test.mkv", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.media = &Media{Duration: duration, Width: width, Height: height}
+	return g
+}
+
+func TestParseDuration(t *testing.T) {
+	const duration = 600000
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{" 90 ", 90000, false},
+		{"90.5", 90500, false},
+		{".5", 500, false},
+		{"5.", 5000, false},
+		{"1:30", 90000, false},
+		{"90:00", 5400000, false},
+		{"01:02:03.5", 3723500, false},
+		{"1h2m", 3720000, false},
+		{"1500ms", 1500, false},
+		{"5%", 30000, false},
+		{"100%", duration, false},
+		{"0%", 0, false},
+		{"-1:00", -60000, false},
+		{"-5%", -30000, false},
+		{"-1h", -3600000, false},
+		{"-0", 0, false},
+		{"-", 0, true},
+		{"--5", 0, true},
+		{"+5", 0, true},
+		{".", 0, true},
+		{"1.2.3", 0, true},
+		{"00:61", 0, true},
+		{"1:60", 0, true},
+		{"1:60:00", 0, true},
+		{"1:2:3:4", 0, true},
+		{"1:-2:03", 0, true},
+		{"1:+2:03", 0, true},
+		{"a:00", 0, true},
+		{"1:xx", 0, true},
+		{"100.5%", 0, true},
+		{"-%", 0, true},
+		{"%", 0, true},
+		{"abc", 0, true},
+		{"5", 5000, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in, duration)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"12", true},
+		{"12.5", true},
+		{".5", true},
+		{"5.", true},
+		{"0", true},
+		{"", false},
+		{".", false},
+		{"-", false},
+		{"-0", false},
+		{"--5", false},
+		{"+5", false},
+		{"1.2.3", false},
+		{"1e3", false},
+		{"100%", false},
+		{"00:61", false},
+		{" 5", false},
+	}
+	for _, tt := range tests {
+		if got := isDecimal(tt.in); got != tt.want {
+			t.Errorf("isDecimal(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// returns a 1024x576 checkerboard of 64px squares in the two shades
+func checkerboard(dark, bright uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 1024, 576))
+	for y := 0; y < 576; y++ {
+		for x := 0; x < 1024; x++ {
+			v := dark
+			if (x/64+y/64)%2 == 0 {
+				v = bright
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return img
+}
+
+func TestSharpness(t *testing.T) {
+	threshold := DefaultOptions().BlurThreshold
+	tests := []struct {
+		name   string
+		img    image.Image
+		blurry bool
+	}{
+		{"sharp", checkerboard(0, 255), false},
+		{"blurred", imaging.Blur(checkerboard(0, 255), 16), true},
+		// the contrast is stretched, dark frames are as sharp as bright ones
+		{"dark but sharp", checkerboard(5, 40), false},
+		{"flat", checkerboard(128, 128), true},
+		{"tiny", checkerboard(0, 255).(*image.Gray).SubImage(image.Rect(0, 0, 2, 2)), true},
+	}
+	for _, tt := range tests {
+		s := sharpness(tt.img)
+		if blurry := s < threshold; blurry != tt.blurry {
+			t.Errorf("%s: sharpness %.2f, blurry = %v, want %v", tt.name, s, blurry, tt.blurry)
+		}
+	}
+
+	if sharp, dark := sharpness(checkerboard(0, 255)), sharpness(checkerboard(5, 40)); dark < sharp/2 {
+		t.Errorf("dark checkerboard has sharpness %.2f, bright one %.2f", dark, sharp)
+	}
+}
+
+// returns a w x h image filled with c
+func solidImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// returns a 640x360 image with a horizontal color gradient in the rows
+// from top on, the ones above are black
+func gradientImage(top int) *image.RGBA {
+	img := solidImage(640, 360, color.Black)
+	for y := top; y < 360; y++ {
+		for x := 0; x < 640; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / 639), uint8(y * 255 / 359), uint8(255 - x*255/639), 255})
+		}
+	}
+	return img
+}
+
+func TestBlankPercent(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+	twoByTwo := solidImage(2, 2, black)
+	twoByTwo.Set(1, 0, color.RGBA{200, 30, 30, 255})
+	twoByTwo.Set(1, 1, color.RGBA{30, 200, 30, 255})
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		min, max int
+	}{
+		// tiny images used to divide by zero
+		{"1x1 black", solidImage(1, 1, black), 100, 100},
+		{"1x1 white", solidImage(1, 1, white), 100, 100},
+		{"2x2 half black", twoByTwo, 50, 50},
+		{"empty", image.NewRGBA(image.Rect(0, 0, 0, 0)), 0, 0},
+		{"black", solidImage(640, 360, black), 100, 100},
+		{"blue screen", solidImage(640, 360, color.RGBA{0, 0, 200, 255}), 100, 100},
+		{"grey card", solidImage(640, 360, color.RGBA{128, 128, 128, 255}), 100, 100},
+		{"mostly black", gradientImage(288), 80, 82},
+		{"gradient", gradientImage(0), 0, 10},
+	}
+	for _, tt := range tests {
+		if got := blankPercent(tt.img); got < tt.min || got > tt.max {
+			t.Errorf("%s: blankPercent = %d, want %d to %d", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+// blankPercent is called by several workers at once, run with -race
+func TestBlankPercentConcurrent(t *testing.T) {
+	images := []image.Image{
+		solidImage(640, 360, color.RGBA{0, 0, 200, 255}),
+		gradientImage(0),
+		gradientImage(288),
+	}
+	want := make([]int, len(images))
+	for i, img := range images {
+		want[i] = blankPercent(img)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 8*len(images))
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, img := range images {
+				if got := blankPercent(img); got != want[i] {
+					errs <- fmt.Sprintf("image %d: blankPercent = %d, want %d", i, got, want[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,187 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// reports if s is a valid entry of Options.Featured
+func validFeatured(s string) bool {
+	switch strings.TrimSpace(s) {
+	case "first", "middle", "last", "best":
+		return true
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	return err == nil && i > 0
+}
+
+// returns the indexes of the screenshots out of n enlarged by the hero
+// layout because of their position and the number of "best" entries, those
+// can only be picked once the frames are decoded
+func (g *generator) featuredPositions(n int) (map[int]bool, int, error) {
+	featured := map[int]bool{}
+	best := 0
+	for _, f := range g.opts.Featured {
+		switch f = strings.TrimSpace(f); f {
+		case "first":
+			featured[0] = true
+		case "middle":
+			featured[(n-1)/2] = true
+		case "last":
+			featured[n-1] = true
+		case "best":
+			best++
+		default:
+			i, _ := strconv.Atoi(f)
+			if i > n {
+				return nil, 0, fmt.Errorf("featured screenshot %d doesn't exist, there are only %d", i, n)
+			}
+			featured[i-1] = true
+		}
+	}
+	return featured, best, nil
+}
+
+// returns the number of featured screenshots out of n
+func (g *generator) featuredCount(n int) int {
+	count := len(g.featured) + g.featuredBest
+	if count > n {
+		count = n
+	}
+	return count
+}
+
+// features the frames with the highest quality scores which aren't
+// featured yet, once for every "best" entry
+func (g *generator) featureBest(scores []float64) {
+	for ; g.featuredBest > 0; g.featuredBest-- {
+		best := -1
+		for i, score := range scores {
+			if !g.featured[i] && (best < 0 || score > scores[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return
+		}
+		g.featured[best] = true
+	}
+}
+
+// reports if featured screenshots span 2x2 tiles instead of a whole row
+func (g *generator) heroBlocks() bool {
+	return g.opts.HeroSize != "row" && g.columns >= 2
+}
+
+// returns the size process scales a frame of size b to
+func (g *generator) tileSize(b image.Rectangle) image.Point {
+	w, h := b.Dx(), b.Dy()
+	switch {
+	case g.opts.Width > 0:
+		return image.Pt(g.opts.Width, int(math.Max(1, math.Floor(float64(g.opts.Width)*float64(h)/float64(w)+0.5))))
+	case g.opts.Height > 0:
+		return image.Pt(int(math.Max(1, math.Floor(float64(g.opts.Height)*float64(w)/float64(h)+0.5))), g.opts.Height)
+	}
+	return image.Pt(w, h)
+}
+
+// returns the size of a featured screenshot for tiles of the given size
+func (g *generator) heroSize(tile image.Point) image.Point {
+	pad := g.padding()
+	if g.heroBlocks() {
+		return image.Pt(2*tile.X+pad, 2*tile.Y+pad)
+	}
+	w := g.columns*tile.X + (g.columns-1)*pad
+	return image.Pt(w, int(math.Round(float64(w)*float64(tile.Y)/float64(tile.X))))
+}
+
+// returns the padding between the screenshots
+func (g *generator) padding() int {
+	if g.opts.Padding > 0 {
+		return g.opts.Padding
+	}
+	return 0
+}
+
+// places n screenshots of the given tile size on the contact sheet and
+// returns their positions and the size of the sheet. Featured screenshots
+// come first, either as 2x2 blocks the other screenshots fill the gaps
+// around or stacked as whole rows above the grid.
+func (g *generator) layout(n int, featured map[int]bool, tile image.Point) ([]image.Rectangle, image.Point) {
+	pad, columns := g.padding(), g.columns
+	if columns < 1 {
+		columns = 1
+	}
+	hero := g.heroSize(tile)
+	rects := make([]image.Rectangle, n)
+
+	var heroes, others []int
+	for i := 0; i < n; i++ {
+		if featured[i] {
+			heroes = append(heroes, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+
+	cell := func(col, row, top int) image.Point {
+		return image.Pt(pad+col*(tile.X+pad), top+pad+row*(tile.Y+pad))
+	}
+	width := columns*tile.X + (columns+1)*pad
+
+	if len(heroes) > 0 && g.heroBlocks() {
+		// place blocks and single tiles into the first free cells
+		used := map[image.Point]bool{}
+		rows := 0
+		place := func(size int) image.Point {
+			for row := 0; ; row++ {
+			next:
+				for col := 0; col+size <= columns; col++ {
+					for y := row; y < row+size; y++ {
+						for x := col; x < col+size; x++ {
+							if used[image.Pt(x, y)] {
+								continue next
+							}
+						}
+					}
+					for y := row; y < row+size; y++ {
+						for x := col; x < col+size; x++ {
+							used[image.Pt(x, y)] = true
+						}
+					}
+					if row+size > rows {
+						rows = row + size
+					}
+					return image.Pt(col, row)
+				}
+			}
+		}
+		for _, i := range heroes {
+			p := place(2)
+			rects[i] = image.Rectangle{Min: cell(p.X, p.Y, 0)}
+			rects[i].Max = rects[i].Min.Add(hero)
+		}
+		for _, i := range others {
+			p := place(1)
+			rects[i] = image.Rectangle{Min: cell(p.X, p.Y, 0)}
+			rects[i].Max = rects[i].Min.Add(tile)
+		}
+		return rects, image.Pt(width, rows*tile.Y+(rows+1)*pad)
+	}
+
+	// whole rows first, the regular grid below them
+	top := 0
+	for _, i := range heroes {
+		rects[i] = image.Rect(pad, top+pad, pad+hero.X, top+pad+hero.Y)
+		top += hero.Y + pad
+	}
+	for k, i := range others {
+		rects[i] = image.Rectangle{Min: cell(k%columns, k/columns, top)}
+		rects[i].Max = rects[i].Min.Add(tile)
+	}
+	rows := (len(others) + columns - 1) / columns
+	return rects, image.Pt(width, top+rows*tile.Y+(rows+1)*pad)
+}
@@ -0,0 +1,74 @@
+package contactsheet
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	tile := image.Pt(100, 50)
+	tests := []struct {
+		name     string
+		n        int
+		columns  int
+		padding  int
+		heroSize string
+		featured map[int]bool
+		want     []image.Rectangle
+		size     image.Point
+	}{
+		{
+			name: "grid", n: 5, columns: 3, padding: 10,
+			want: []image.Rectangle{
+				image.Rect(10, 10, 110, 60), image.Rect(120, 10, 220, 60), image.Rect(230, 10, 330, 60),
+				image.Rect(10, 70, 110, 120), image.Rect(120, 70, 220, 120),
+			},
+			size: image.Pt(340, 130),
+		},
+		{
+			name: "grid without padding", n: 3, columns: 2,
+			want: []image.Rectangle{image.Rect(0, 0, 100, 50), image.Rect(100, 0, 200, 50), image.Rect(0, 50, 100, 100)},
+			size: image.Pt(200, 100),
+		},
+		{
+			name: "2x2 hero", n: 6, columns: 3, padding: 10, heroSize: "2x2", featured: map[int]bool{0: true},
+			want: []image.Rectangle{
+				image.Rect(10, 10, 220, 120), image.Rect(230, 10, 330, 60), image.Rect(230, 70, 330, 120),
+				image.Rect(10, 130, 110, 180), image.Rect(120, 130, 220, 180), image.Rect(230, 130, 330, 180),
+			},
+			size: image.Pt(340, 190),
+		},
+		{
+			name: "2x2 heroes side by side", n: 3, columns: 4, heroSize: "2x2", featured: map[int]bool{1: true, 2: true},
+			want: []image.Rectangle{image.Rect(0, 100, 100, 150), image.Rect(0, 0, 200, 100), image.Rect(200, 0, 400, 100)},
+			size: image.Pt(400, 150),
+		},
+		{
+			name: "row hero", n: 4, columns: 3, padding: 10, heroSize: "row", featured: map[int]bool{1: true},
+			want: []image.Rectangle{
+				image.Rect(10, 180, 110, 230), image.Rect(10, 10, 330, 170), image.Rect(120, 180, 220, 230), image.Rect(230, 180, 330, 230),
+			},
+			size: image.Pt(340, 240),
+		},
+		{
+			// blocks need at least 2 columns, a single one uses rows
+			name: "single column", n: 2, columns: 1, heroSize: "2x2", featured: map[int]bool{0: true},
+			want: []image.Rectangle{image.Rect(0, 0, 100, 50), image.Rect(0, 50, 100, 100)},
+			size: image.Pt(100, 100),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &generator{columns: tt.columns}
+			g.opts.Padding, g.opts.HeroSize = tt.padding, tt.heroSize
+			rects, size := g.layout(tt.n, tt.featured, tile)
+			if !reflect.DeepEqual(rects, tt.want) {
+				t.Errorf("got %v, want %v", rects, tt.want)
+			}
+			if size != tt.size {
+				t.Errorf("got size %v, want %v", size, tt.size)
+			}
+		})
+	}
+}
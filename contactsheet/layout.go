@@ -0,0 +1,117 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// tiles smaller than this (in px) are never used by the auto layout
+const layoutMinTile = 32
+
+// parses an aspect ratio like "16:9" or "1.78", 0 is returned for an empty
+// string
+func parseAspect(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	var a float64
+	var err error
+	if parts := strings.SplitN(s, ":", 2); len(parts) == 2 {
+		var w, h float64
+		if w, err = strconv.ParseFloat(parts[0], 64); err == nil {
+			if h, err = strconv.ParseFloat(parts[1], 64); err == nil && h != 0 {
+				a = w / h
+			}
+		}
+	} else {
+		a, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil || a <= 0 || math.IsInf(a, 0) || math.IsNaN(a) {
+		return 0, fmt.Errorf("invalid aspect ratio %q, use width:height or a number like 1.5", s)
+	}
+	return a, nil
+}
+
+// computes the columns and the width of the screenshots for n screenshots
+// so the contact sheet is Options.SheetWidth wide and, if set, at most
+// Options.SheetMaxHeight high (without the header). Of all grids fitting
+// it the one closest to Options.SheetAspect is used, by default the one
+// filling SheetMaxHeight best or with about as many rows as columns.
+// Featured screenshots of the hero layout are included.
+func (g *generator) autoLayout(n int) error {
+	if g.opts.SheetWidth <= 0 || n <= 0 {
+		return nil
+	}
+
+	// the aspect of the screenshots, after cropping black bars
+	w, h := g.media.Width, g.media.Height
+	if !g.media.Crop.Empty() {
+		w, h = g.media.Crop.Dx(), g.media.Crop.Dy()
+	}
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("auto layout needs the resolution of the video")
+	}
+	frameAspect := float64(w) / float64(h)
+
+	target, err := parseAspect(g.opts.SheetAspect)
+	if err != nil {
+		return err
+	}
+	if target == 0 && g.opts.SheetMaxHeight > 0 {
+		target = float64(g.opts.SheetWidth) / float64(g.opts.SheetMaxHeight)
+	} else if target == 0 {
+		target = frameAspect
+	}
+
+	// only the number of featured screenshots matters for the size
+	featured := map[int]bool{}
+	for i := 0; i < g.featuredCount(n); i++ {
+		featured[i] = true
+	}
+
+	pad := g.padding()
+	bestColumns, bestWidth, bestHeight := 0, 0, 0
+	bestFits, bestDist := false, 0.0
+	for c := 1; c <= n; c++ {
+		tile := (g.opts.SheetWidth - (c+1)*pad) / c
+		if tile < layoutMinTile {
+			break
+		}
+		g.columns = c
+		_, size := g.layout(n, featured, image.Pt(tile, int(math.Round(float64(tile)/frameAspect))))
+		height := size.Y
+		fits := g.opts.SheetMaxHeight <= 0 || height <= g.opts.SheetMaxHeight
+		dist := math.Abs(math.Log(float64(g.opts.SheetWidth) / float64(height) / target))
+
+		// grids which fit always win, if none does the lowest one is used
+		var better bool
+		switch {
+		case bestColumns == 0:
+			better = true
+		case fits != bestFits:
+			better = fits
+		case fits:
+			better = dist < bestDist
+		default:
+			better = height < bestHeight
+		}
+		if better {
+			bestColumns, bestWidth, bestHeight = c, tile, height
+			bestFits, bestDist = fits, dist
+		}
+	}
+	if bestColumns == 0 {
+		return fmt.Errorf("sheet width %d is too small for a screenshot", g.opts.SheetWidth)
+	}
+	if !bestFits {
+		g.log.Warnf("%d screenshots don't fit into %dx%d, the contact sheet will be %d high", n, g.opts.SheetWidth, g.opts.SheetMaxHeight, bestHeight)
+	}
+
+	g.columns = bestColumns
+	g.opts.Width, g.opts.Height = bestWidth, 0
+	g.log.Infof("auto layout: %d columns of %dx%d screenshots, %dx%d without the header", bestColumns, bestWidth, int(math.Round(float64(bestWidth)/frameAspect)), bestColumns*bestWidth+(bestColumns+1)*pad, bestHeight)
+	return nil
+}
@@ -0,0 +1,116 @@
+package contactsheet
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseAspect(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"16:9", 16.0 / 9, false},
+		{"1.5", 1.5, false},
+		{"4:3", 4.0 / 3, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"16:0", 0, true},
+		{"0:9", 0, true},
+		{"a:b", 0, true},
+		{"16:", 0, true},
+		{"wide", 0, true},
+		{"Inf", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAspect(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAspect(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAspect(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAutoLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int // of the video
+		crop          image.Rectangle
+		n             int
+		sheetWidth    int
+		maxHeight     int
+		aspect        string
+		padding       int
+		heroSize      string
+		featured      []string // uses the hero layout if set
+		columns       int
+		tileWidth     int
+		wantErr       bool
+	}{
+		// 3x3 tiles of 333x187 come closest to the 16:9 of the frames
+		{name: "square grid", width: 1920, height: 1080, n: 9, sheetWidth: 1000, columns: 3, tileWidth: 333},
+		// 5 columns are the narrowest grid which is at most 400 high
+		{name: "max height", width: 1920, height: 1080, n: 9, sheetWidth: 1000, maxHeight: 400, columns: 5, tileWidth: 200},
+		// nothing fits, the lowest grid is used
+		{name: "too high", width: 1920, height: 1080, n: 9, sheetWidth: 1000, maxHeight: 50, columns: 9, tileWidth: 111},
+		{name: "aspect with padding", width: 1920, height: 1080, n: 4, sheetWidth: 1000, aspect: "16:9", padding: 10, columns: 2, tileWidth: 485},
+		{name: "wide sheet", width: 1920, height: 1080, n: 4, sheetWidth: 1000, aspect: "8", columns: 4, tileWidth: 250},
+		// the cropped frames are 2.4:1, two columns of them are closest to 1:1
+		{name: "cropped", width: 1920, height: 1080, crop: image.Rect(0, 140, 1920, 940), n: 6, sheetWidth: 1000, aspect: "1", columns: 2, tileWidth: 500},
+		{name: "portrait", width: 1080, height: 1920, n: 8, sheetWidth: 1000, aspect: "16:9", columns: 6, tileWidth: 166},
+		// the featured row is as high as a whole 16:9 sheet row
+		{name: "row hero", width: 1920, height: 1080, n: 8, sheetWidth: 1200, maxHeight: 1000, heroSize: "row", featured: []string{"first"}, columns: 5, tileWidth: 240},
+		{name: "2x2 hero", width: 1920, height: 1080, n: 6, sheetWidth: 1000, maxHeight: 600, heroSize: "2x2", featured: []string{"best"}, columns: 3, tileWidth: 333},
+		{name: "disabled", width: 1920, height: 1080, n: 9, columns: 4, tileWidth: 400},
+		{name: "too narrow", width: 1920, height: 1080, n: 9, sheetWidth: 20, wantErr: true},
+		{name: "unknown resolution", n: 9, sheetWidth: 1000, wantErr: true},
+		{name: "invalid aspect", width: 1920, height: 1080, n: 9, sheetWidth: 1000, aspect: "x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Columns, opts.Width = 4, 400
+			opts.SheetWidth, opts.SheetMaxHeight, opts.SheetAspect = tt.sheetWidth, tt.maxHeight, tt.aspect
+			opts.Padding = tt.padding
+			if tt.featured != nil {
+				opts.Layout, opts.HeroSize, opts.Featured = "hero", tt.heroSize, tt.featured
+			}
+			// not created by newGenerator, it would reject the invalid aspect
+			g := &generator{opts: opts, log: testLogger(), columns: opts.Columns}
+			g.media = &Media{Width: tt.width, Height: tt.height, Crop: tt.crop}
+			if tt.featured != nil {
+				var err error
+				if g.featured, g.featuredBest, err = g.featuredPositions(tt.n); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := g.autoLayout(tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if g.columns != tt.columns || g.opts.Width != tt.tileWidth {
+				t.Errorf("got %d columns of width %d, want %d of width %d", g.columns, g.opts.Width, tt.columns, tt.tileWidth)
+			}
+			if tt.sheetWidth > 0 && g.opts.Height != 0 {
+				t.Errorf("height = %d, want 0", g.opts.Height)
+			}
+			if tt.maxHeight > 0 && tt.name != "too high" {
+				// the best frames are picked after decoding
+				g.featureBest(make([]float64, tt.n))
+				tile := g.tileSize(image.Rect(0, 0, tt.width, tt.height))
+				if _, size := g.layout(tt.n, g.featured, tile); size.Y > tt.maxHeight || size.X > tt.sheetWidth {
+					t.Errorf("sheet is %v, want at most %dx%d", size, tt.sheetWidth, tt.maxHeight)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gitlab.com/opennota/screengen"
+)
+
+// Media holds the informations of a video file, it is probed once per
+// Generate call and shared by everything that needs them
+type Media struct {
+	Path               string    `json:"path"`     // path or URL of the video
+	Filename           string    `json:"filename"` // file name, for URLs taken from Content-Disposition if available
+	Size               int64     `json:"size"`     // size in bytes, -1 if unknown
+	Duration           int64     `json:"duration"` // duration in milliseconds
+	Width              int       `json:"width"`
+	Height             int       `json:"height"`
+	FPS                float64   `json:"fps"`
+	Bitrate            int       `json:"bitrate"`
+	VideoCodec         string    `json:"video_codec"`
+	VideoCodecLongName string    `json:"video_codec_long_name"`
+	AudioCodec         string    `json:"audio_codec"`
+	AudioCodecLongName string    `json:"audio_codec_long_name"`
+	Chapters           []Chapter `json:"chapters"`
+
+	// Crop is the area of the frames without black bars, the whole frame
+	// unless Options.AutoCrop detected some
+	Crop image.Rectangle `json:"crop"`
+
+	// the decoder opened while probing, reused for taking screenshots
+	gen *screengen.Generator
+}
+
+// opens the video and reads all file informations, the returned media
+// has to be closed by the caller
+func (g *generator) probe() (*Media, error) {
+	gen, err := screengen.NewGenerator(g.fn)
+	if err != nil {
+		return nil, fmt.Errorf("error reading video file: %v", err)
+	}
+	gen.Fast = g.opts.Fast
+
+	m := &Media{
+		Path:               g.fn,
+		Size:               -1,
+		Duration:           gen.Duration,
+		Width:              gen.Width(),
+		Height:             gen.Height(),
+		FPS:                gen.FPS,
+		Bitrate:            gen.Bitrate,
+		VideoCodec:         gen.VideoCodec,
+		VideoCodecLongName: gen.VideoCodecLongName,
+		AudioCodec:         gen.AudioCodec,
+		AudioCodecLongName: gen.AudioCodecLongName,
+		Crop:               image.Rect(0, 0, gen.Width(), gen.Height()),
+		gen:                gen,
+	}
+	_, m.Filename = filepath.Split(g.fn)
+
+	if stat, err := os.Stat(g.fn); err == nil {
+		m.Size = stat.Size()
+		if m.Chapters, err = readChapters(g.fn, m.Duration); err != nil {
+			g.log.Debugf("unable to read chapters: %v", err)
+		}
+	} else if _, err := url.ParseRequestURI(g.fn); err == nil {
+		// try if it is a web video
+		g.probeHTTP(m)
+	}
+
+	return m, nil
+}
+
+// reads size and filename of a web video, failures only log a warning
+// as the video itself could already be opened
+func (g *generator) probeHTTP(m *Media) {
+	resp, err := http.Head(m.Path)
+	if err != nil {
+		g.log.Warnf("unable to read file informations: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		m.Size = size
+	}
+
+	cdisposition := resp.Header.Get("Content-Disposition")
+	_, params, _ := mime.ParseMediaType(cdisposition)
+	if params["filename"] != "" {
+		m.Filename = params["filename"] // prefer filename to the name split from url
+	}
+}
+
+// releases the decoder opened while probing
+func (m *Media) close() error {
+	if m.gen == nil {
+		return nil
+	}
+	err := m.gen.Close()
+	m.gen = nil
+	return err
+}
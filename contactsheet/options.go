@@ -0,0 +1,197 @@
+package contactsheet
+
+import (
+	"image/color"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Options holds all settings used to generate a contact sheet
+type Options struct {
+	Numcaps int // number of screenshots to take
+	Columns int // how many columns should be used
+	Padding int // padding around the images in px
+	Width   int // width of a single screenshot, 0 scales by Height
+	Height  int // height of a single screenshot, only used if Width is 0
+
+	// SheetWidth enables the auto layout: Columns, Width and Height are
+	// computed so the contact sheet is at most SheetWidth wide and, if set,
+	// SheetMaxHeight high (without the header). Of all fitting grids the one
+	// closest to SheetAspect ("16:9" or "1.78") is used, by default the one
+	// filling SheetMaxHeight best or with about as many rows as columns.
+	SheetWidth     int
+	SheetMaxHeight int
+	SheetAspect    string
+
+	// Layout is "grid" (default) or "hero" which enlarges the Featured
+	// screenshots ("first", "middle", "last", "best" for the highest
+	// quality or a number starting at 1) and puts them at the top of the
+	// sheet, HeroSize is "2x2" (default) for a block of 2x2 screenshots the
+	// others are placed around or "row" for the full width of the sheet
+	Layout   string
+	Featured []string
+	HeroSize string
+
+	// From, To and Interval accept timestamps (HH:MM:SS(.ms) or MM:SS),
+	// plain seconds ("90"), Go durations ("1h2m") or percentages ("5%"), a
+	// leading "-" counts From and To from the end of the clip
+	From        string // starting point
+	To          string // end point
+	Interval    string // take a screenshot every Interval, overwrites Numcaps
+	SkipCredits bool   // detect the credits at the end of the clip and leave them out, ignored if To is set
+	SkipIntro   bool   // detect an intro at the start of the clip and leave it out, ignored if From is set
+	Fast        bool   // faster but inaccurate seeking
+	Workers     int    // number of screenshots extracted in parallel, 0 uses all CPUs
+	FastScale   bool   // let the decoder scale frames close to the final size before resizing them
+	AutoCrop    bool   // detect black bars (letterbox and pillarbox) and crop them from all screenshots
+
+	// Select chooses how screenshots are spread over the video: "even"
+	// (default) uses the same distance for all screenshots, "scenes" scans
+	// SceneSamples frames for cuts and takes one screenshot per scene,
+	// "chapters" takes ChapterCaps screenshots per chapter of the container
+	// and adds the chapter title to the timestamps
+	Select       string
+	SceneSamples int // frames sampled for scene detection, 0 uses 20 per screenshot but at least 200
+	ChapterCaps  int // screenshots per chapter, defaults to 1
+
+	// Ranges spreads the screenshots over several parts of the video,
+	// proportionally to their length, instead of one. Every range is given
+	// as "start-end" in the same formats as From. From, To, SkipIntro,
+	// SkipCredits and Select are ignored.
+	Ranges []string
+
+	// At takes screenshots at exactly these timestamps (same formats as
+	// From) instead of spreading them over the video, Numcaps, Interval,
+	// From, To and Select are ignored
+	At []string
+
+	Font              string  // font name or path used for timestamps and header
+	FontSize          int     // font size in px
+	DisableTimestamps bool    // don't draw timestamps onto the screenshots
+	TimestampOpacity  float64 // opacity of the timestamps from 0.0 to 1.0
+
+	// TimestampFormat sets how timestamps are drawn: "hms" (default,
+	// HH:MM:SS), "hms.ms" (HH:MM:SS.mmm), "smpte" (HH:MM:SS:FF timecode with
+	// the frame number), "seconds" (123.456s) or "percent" (of the duration)
+	TimestampFormat string
+
+	// SingleImages skips the contact sheet creation, the prepared
+	// screenshots are returned in Result.Frames only
+	SingleImages bool
+
+	BgContent   color.Color // background color of the content area
+	BgHeader    color.Color // background color of the header
+	FgHeader    color.Color // font color of the header
+	Header      bool        // prepend a header with file informations
+	HeaderMeta  bool        // add codec, bitrate and FPS to the header
+	HeaderImage string      // path to an image added to the right of the header
+	Comment     string      // comment added as last line of the header
+
+	Watermark    string   // path to an image added to the middle screenshot
+	WatermarkAll string   // path to an image added to the bottom left of every screenshot
+	Filters      []string // image filters applied to every screenshot, see Filters
+
+	// FancyMinAngle and FancyMaxAngle are the range of degrees the "fancy"
+	// filter rotates the screenshots by
+	FancyMinAngle int
+	FancyMaxAngle int
+
+	// Seed makes all random decisions (like the rotation of the "fancy"
+	// filter) reproducible, 0 uses a hash of the video path so every run
+	// for the same video creates the same contact sheet
+	Seed int64
+
+	SkipBlank      bool    // retry if a frame is considered blank
+	SkipBlurry     bool    // retry if a frame is considered blurry
+	SFW            bool    // retry if a frame is considered nude
+	BlurThreshold  float64 // frames with a lower sharpness (variance of the laplacian) are considered blurry
+	BlankThreshold int     // percentage of dark/white or same colored pixels to consider a frame blank
+
+	// Censor keeps frames flagged by the nudity detection but makes them
+	// unrecognizable with "blur" or "pixelate". With SFW these are only the
+	// frames still flagged after all retries. The detection runs on the
+	// decoded frames like the nude rejector. CensorArea is "frame"
+	// (default) or "skin" for the detected skin regions only, CensorLabel
+	// is stamped in the middle of censored frames and the header lists them.
+	Censor      string
+	CensorArea  string
+	CensorLabel string
+
+	// Retries is the maximum number of other frames tried for a skipped
+	// frame, each RetryStep (a time value like "10s" or a percentage of the
+	// distance to the next screenshot like "10%") away from the original
+	// one. RetryDirection is "forward" (default), "backward" or "alternate"
+	// (after, before, further after, ...). If all frames are skipped the
+	// last one is used or, with RetryFallback "best", the one with the
+	// highest quality.
+	Retries        int
+	RetryStep      string
+	RetryDirection string
+	RetryFallback  string
+
+	// Candidates takes this many frames around every timestamp, each
+	// CandidateStep (a time value or a percentage of the distance to the next
+	// screenshot) apart, and keeps the one with the highest quality based on
+	// sharpness, brightness, contrast and colorfulness. Frames skipped by
+	// the rejectors are only used if all candidates are.
+	Candidates    int
+	CandidateStep string
+
+	// SkipDuplicates retries if a frame looks like one of the
+	// frames before it, that is if the distance of their perceptual hashes
+	// (0-64) is below DuplicateThreshold
+	SkipDuplicates     bool
+	DuplicateThreshold int
+
+	// Reject is the ordered chain of rejectors checking every frame, given
+	// as name with an optional threshold like "blank:80", "blurry:20" or
+	// "duplicate:6" (see RegisterRejector). Rejected frames are retried like
+	// above. If empty the chain is built from SkipBlurry, SkipBlank, SFW
+	// and SkipDuplicates with their thresholds.
+	Reject []string
+
+	// AnalyzeSamples is the number of frames Analyze spreads over the video
+	// like Numcaps, 0 analyzes the frames Generate would take
+	AnalyzeSamples int
+
+	// Logger receives all log output of the generation, defaults to the
+	// logrus standard logger
+	Logger log.FieldLogger
+}
+
+// DefaultOptions returns the options mt uses if nothing else is configured
+func DefaultOptions() Options {
+	return Options{
+		Numcaps:            4,
+		Columns:            2,
+		Padding:            10,
+		Width:              400,
+		From:               "00:00:00",
+		To:                 "00:00:00",
+		Font:               "DroidSans.ttf",
+		FontSize:           12,
+		TimestampOpacity:   1.0,
+		TimestampFormat:    "hms",
+		BgContent:          color.RGBA{0, 0, 0, 255},
+		BgHeader:           color.RGBA{0, 0, 0, 255},
+		FgHeader:           color.RGBA{255, 255, 255, 255},
+		Header:             true,
+		Comment:            "contactsheet created with mt (https://github.com/mutschler/mt)",
+		Filters:            []string{"none"},
+		FancyMinAngle:      -10,
+		FancyMaxAngle:      15,
+		BlurThreshold:      25,
+		BlankThreshold:     85,
+		DuplicateThreshold: 6,
+		Retries:            3,
+		RetryStep:          "10s",
+		RetryDirection:     "forward",
+		RetryFallback:      "last",
+		Candidates:         1,
+		CandidateStep:      "1s",
+		CensorArea:         "frame",
+		Layout:             "grid",
+		Featured:           []string{"best"},
+		HeroSize:           "2x2",
+	}
+}
@@ -0,0 +1,127 @@
+package contactsheet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// a part of the video screenshots are taken from, in milliseconds
+type timeRange struct {
+	start, end int64
+}
+
+func (r timeRange) String() string {
+	return fmt.Sprintf("%s-%s", formatTimestamp(r.start), formatTimestamp(r.end))
+}
+
+// parses Options.Ranges, the returned ranges are sorted and overlapping
+// ones are merged
+func (g *generator) parseRanges() ([]timeRange, error) {
+	length := 1000 * (g.media.Duration / 1000)
+	var ranges []timeRange
+	for _, s := range g.opts.Ranges {
+		s = strings.TrimSpace(s)
+		// the first character may be the sign of the start
+		sep := -1
+		if len(s) > 1 {
+			sep = strings.Index(s[1:], "-") + 1
+		}
+		if sep < 1 {
+			return nil, fmt.Errorf("invalid range %q, use start-end", s)
+		}
+
+		var r timeRange
+		for i, part := range []string{s[:sep], s[sep+1:]} {
+			v, err := parseDuration(part, length)
+			if err != nil {
+				return nil, fmt.Errorf("range %s: %v", s, err)
+			}
+			if v < 0 {
+				v = length + v
+			}
+			if v < 0 || v > length {
+				return nil, fmt.Errorf("range %s is outside of the video (%s)", s, formatTimestamp(length))
+			}
+			if i == 0 {
+				r.start = v
+			} else {
+				r.end = v
+			}
+		}
+		if r.start >= r.end {
+			return nil, fmt.Errorf("range %s has to start before it ends", s)
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.start <= merged[n-1].end {
+			if r.end > merged[n-1].end {
+				merged[n-1].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// spreads the screenshots over g.ranges proportionally to their length,
+// as if they were one continuous clip
+func (g *generator) rangeTimestamps() ([]int64, error) {
+	var total int64
+	for _, r := range g.ranges {
+		total += r.end - r.start
+	}
+
+	interval, err := parseDuration(g.opts.Interval, total)
+	if err != nil {
+		return nil, fmt.Errorf("interval: %v", err)
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("interval: %s can't be negative", g.opts.Interval)
+	}
+	if interval > 0 {
+		if total < interval {
+			return nil, errors.New("specified interval is longer than the ranges, " +
+				"use smaller interval or set numcaps instead")
+		}
+		g.numcaps = int(total / interval)
+		g.log.Debugf("interval option set, numcaps are set to %d", g.numcaps)
+		g.columns = int(math.Sqrt(float64(g.numcaps)))
+	}
+	if g.numcaps <= 0 {
+		return nil, fmt.Errorf("invalid number of captures: %d", g.numcaps)
+	}
+
+	// every screenshot is taken in the middle of its share of the ranges
+	stamps := make([]int64, g.numcaps)
+	for i := range stamps {
+		pos := total * int64(2*i+1) / int64(2*g.numcaps)
+		for _, r := range g.ranges {
+			if pos < r.end-r.start {
+				stamps[i] = r.start + pos
+				break
+			}
+			pos -= r.end - r.start
+		}
+	}
+	g.log.Debugf("spreading %d screenshots over %d ranges", g.numcaps, len(g.ranges))
+	return stamps, nil
+}
+
+// returns the range containing ts, ok is false if there are no ranges or
+// ts is outside of all of them
+func (g *generator) rangeOf(ts int64) (timeRange, bool) {
+	for _, r := range g.ranges {
+		if ts >= r.start && ts <= r.end {
+			return r, true
+		}
+	}
+	return timeRange{}, false
+}
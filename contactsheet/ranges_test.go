@@ -0,0 +1,51 @@
+package contactsheet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  []string
+		want    []timeRange
+		wantErr bool
+	}{
+		{"single", []string{"1:00-2:00"}, []timeRange{{60000, 120000}}, false},
+		{"spaces", []string{" 1:00-2:00 "}, []timeRange{{60000, 120000}}, false},
+		{"percentages", []string{"50%-100%"}, []timeRange{{300000, 600000}}, false},
+		{"relative to the end", []string{"-2:00--1:00"}, []timeRange{{480000, 540000}}, false},
+		{"whole video", []string{"0-10:00"}, []timeRange{{0, 600000}}, false},
+		{"sorted", []string{"5:00-6:00", "0-1:00"}, []timeRange{{0, 60000}, {300000, 360000}}, false},
+		{"overlapping", []string{"1:00-3:00", "5:00-6:00", "2:00-4:00"}, []timeRange{{60000, 240000}, {300000, 360000}}, false},
+		{"touching", []string{"0-1:00", "1:00-2:00"}, []timeRange{{0, 120000}}, false},
+		{"contained", []string{"1:00-5:00", "2:00-3:00"}, []timeRange{{60000, 300000}}, false},
+		{"no end", []string{"1:00"}, nil, true},
+		{"empty end", []string{"1:00-"}, nil, true},
+		{"only a dash", []string{"-"}, nil, true},
+		{"empty", []string{""}, nil, true},
+		{"negative only", []string{"-10"}, nil, true},
+		{"reversed", []string{"2:00-1:00"}, nil, true},
+		{"empty range", []string{"1:00-1:00"}, nil, true},
+		{"after the end", []string{"0-11:00"}, nil, true},
+		{"before the start", []string{"-11:00-0"}, nil, true},
+		{"not a time", []string{"a-b"}, nil, true},
+		{"one invalid", []string{"0-1:00", "x-2:00"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Ranges = tt.ranges
+			// the duration is truncated to full seconds
+			g := testGenerator(t, opts, 600500, 1920, 1080)
+			got, err := g.parseRanges()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
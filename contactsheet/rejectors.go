@@ -0,0 +1,287 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koyachi/go-nude"
+)
+
+// FrameRejector decides if a screenshot should be skipped and another frame
+// be taken instead. Check is called from several goroutines at once.
+type FrameRejector interface {
+	// Name identifies the rejector in logs and Result.Rejections
+	Name() string
+	// Check returns the score of img and if it should be rejected, frames
+	// are never rejected because of an error
+	Check(img image.Image) (score float64, reject bool, err error)
+}
+
+// SequentialRejector is a FrameRejector which compares frames to the ones
+// before them. It is checked after all other rejectors, one frame at a time
+// in order of the timestamps, and Accept is called for every used frame.
+// The frames can already be scaled down to their size on the contact sheet.
+type SequentialRejector interface {
+	FrameRejector
+	Accept(img image.Image)
+}
+
+// RejectorFactory creates a rejector for a single Generate call, threshold
+// is the part after the colon of an Options.Reject entry or empty to use
+// the default
+type RejectorFactory func(threshold string) (FrameRejector, error)
+
+var (
+	rejectorsMu sync.RWMutex
+	rejectors   = map[string]RejectorFactory{
+		"blank":     newBlankRejector,
+		"blurry":    newBlurryRejector,
+		"nude":      newNudeRejector,
+		"duplicate": newDuplicateRejector,
+	}
+)
+
+// RegisterRejector makes a rejector available to Options.Reject under name,
+// an existing rejector with the same name is replaced
+func RegisterRejector(name string, factory RejectorFactory) {
+	rejectorsMu.Lock()
+	defer rejectorsMu.Unlock()
+	rejectors[name] = factory
+}
+
+// Rejectors returns the names of all registered rejectors
+func Rejectors() []string {
+	rejectorsMu.RLock()
+	defer rejectorsMu.RUnlock()
+	var names []string
+	for name := range rejectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// returns the rejectors configured by Options.Reject or, if that is empty,
+// by SkipBlurry, SkipBlank, SFW and SkipDuplicates
+func (g *generator) newRejectors() ([]FrameRejector, error) {
+	if len(g.opts.Reject) == 0 {
+		var chain []FrameRejector
+		if g.opts.SkipBlurry {
+			chain = append(chain, blurryRejector{g.opts.BlurThreshold})
+		}
+		if g.opts.SkipBlank {
+			chain = append(chain, blankRejector{g.opts.BlankThreshold})
+		}
+		if g.opts.SFW {
+			chain = append(chain, nudeRejector{})
+		}
+		if g.opts.SkipDuplicates {
+			chain = append(chain, &duplicateRejector{threshold: g.opts.DuplicateThreshold})
+		}
+		return chain, nil
+	}
+
+	var chain []FrameRejector
+	for _, entry := range g.opts.Reject {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		rejectorsMu.RLock()
+		factory, ok := rejectors[parts[0]]
+		rejectorsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown rejector: %s, use one of %s", parts[0], strings.Join(Rejectors(), ", "))
+		}
+		threshold := ""
+		if len(parts) == 2 {
+			threshold = parts[1]
+		}
+		r, err := factory(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("rejector %s: %v", parts[0], err)
+		}
+		chain = append(chain, r)
+	}
+	return chain, nil
+}
+
+// reports if any of the (sequential) rejectors is used
+func (g *generator) hasRejectors(sequential bool) bool {
+	for _, r := range g.rejectors {
+		if _, ok := r.(SequentialRejector); ok == sequential {
+			return true
+		}
+	}
+	return false
+}
+
+// decides if the image taken at ts should be skipped by one of the
+// rejectors, sequential ones are only used by skipSequential
+func (g *generator) skipImage(img image.Image, ts int64) bool {
+	return g.checkRejectors(img, ts, false)
+}
+
+// decides if the image taken at ts should be skipped by one of the
+// sequential rejectors
+func (g *generator) skipSequential(img image.Image, ts int64) bool {
+	return g.checkRejectors(img, ts, true)
+}
+
+func (g *generator) checkRejectors(img image.Image, ts int64, sequential bool) bool {
+	for _, r := range g.rejectors {
+		if _, ok := r.(SequentialRejector); ok != sequential {
+			continue
+		}
+		score, reject, err := r.Check(img)
+		if err != nil {
+			g.log.Errorf("%s check of frame at %s failed: %v", r.Name(), formatTimestamp(ts), err)
+			continue
+		}
+		g.log.Debugf("%s score of frame at %s: %.2f", r.Name(), formatTimestamp(ts), score)
+		if reject {
+			g.log.Infof("frame at %s rejected by %s (score %.2f)", formatTimestamp(ts), r.Name(), score)
+			g.rejectedMu.Lock()
+			g.rejected[r.Name()]++
+			g.rejectedMu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
+// tells the sequential rejectors about a used frame
+func (g *generator) accept(img image.Image) {
+	for _, r := range g.rejectors {
+		if s, ok := r.(SequentialRejector); ok {
+			s.Accept(img)
+		}
+	}
+}
+
+// logs how many frames every rejector rejected
+func (g *generator) logRejections() {
+	var summary []string
+	total := 0
+	for _, r := range g.rejectors {
+		if n := g.rejected[r.Name()]; n > 0 {
+			summary = append(summary, fmt.Sprintf("%s %d", r.Name(), n))
+			total += n
+		}
+	}
+	if total > 0 {
+		g.log.Infof("rejected %d frames: %s", total, strings.Join(summary, ", "))
+	}
+}
+
+// rejects frames with at least threshold percent of dark, white or same
+// colored pixels, the score is that percentage
+type blankRejector struct {
+	threshold int
+}
+
+func newBlankRejector(threshold string) (FrameRejector, error) {
+	r := blankRejector{DefaultOptions().BlankThreshold}
+	if threshold != "" {
+		t, err := strconv.Atoi(threshold)
+		if err != nil || t < 0 || t > 100 {
+			return nil, fmt.Errorf("threshold has to be a percentage: %s", threshold)
+		}
+		r.threshold = t
+	}
+	return r, nil
+}
+
+func (r blankRejector) Name() string { return "blank" }
+
+func (r blankRejector) Check(img image.Image) (float64, bool, error) {
+	p := blankPercent(img)
+	return float64(p), p >= r.threshold, nil
+}
+
+// rejects frames with a sharpness below threshold, the score is the
+// sharpness
+type blurryRejector struct {
+	threshold float64
+}
+
+func newBlurryRejector(threshold string) (FrameRejector, error) {
+	r := blurryRejector{DefaultOptions().BlurThreshold}
+	if threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || t < 0 {
+			return nil, fmt.Errorf("threshold has to be a positive number: %s", threshold)
+		}
+		r.threshold = t
+	}
+	return r, nil
+}
+
+func (r blurryRejector) Name() string { return "blurry" }
+
+func (r blurryRejector) Check(img image.Image) (float64, bool, error) {
+	s := sharpness(img)
+	return s, s < r.threshold, nil
+}
+
+// rejects frames showing nudity, the score is 1 for those and 0 otherwise
+type nudeRejector struct{}
+
+func newNudeRejector(threshold string) (FrameRejector, error) {
+	if threshold != "" {
+		return nil, fmt.Errorf("doesn't take a threshold")
+	}
+	return nudeRejector{}, nil
+}
+
+func (r nudeRejector) Name() string { return "nude" }
+
+func (r nudeRejector) Check(img image.Image) (float64, bool, error) {
+	isNude, err := nude.IsImageNude(img)
+	if err != nil || !isNude {
+		return 0, false, err
+	}
+	return 1, true, nil
+}
+
+// rejects frames whose perceptual hash has a distance below threshold to
+// one of the used frames, the score is the smallest distance
+type duplicateRejector struct {
+	threshold int
+	accepted  []uint64
+}
+
+func newDuplicateRejector(threshold string) (FrameRejector, error) {
+	r := &duplicateRejector{threshold: DefaultOptions().DuplicateThreshold}
+	if threshold != "" {
+		t, err := strconv.Atoi(threshold)
+		if err != nil || t < 0 || t > 64 {
+			return nil, fmt.Errorf("threshold has to be a distance from 0 to 64: %s", threshold)
+		}
+		r.threshold = t
+	}
+	return r, nil
+}
+
+func (r *duplicateRejector) Name() string { return "duplicate" }
+
+func (r *duplicateRejector) Check(img image.Image) (float64, bool, error) {
+	hash := dHash(img)
+	min := 64
+	for _, a := range r.accepted {
+		if distance := bits.OnesCount64(hash ^ a); distance < min {
+			min = distance
+		}
+	}
+	return float64(min), min < r.threshold, nil
+}
+
+func (r *duplicateRejector) Accept(img image.Image) {
+	r.accepted = append(r.accepted, dHash(img))
+}
@@ -0,0 +1,143 @@
+package contactsheet
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/disintegration/imaging"
+	"gitlab.com/opennota/screengen"
+)
+
+// minimum mean luma difference (0-255) between two samples to count as cut
+const sceneCutThreshold = 12
+
+// width of the thumbnails compared for scene detection
+const sceneSampleWidth = 64
+
+// returns the number of frames sampled for scene detection
+func (g *generator) sceneSamples() int {
+	if g.opts.SceneSamples > 0 {
+		return g.opts.SceneSamples
+	}
+	samples := 20 * g.numcaps
+	if samples < 200 {
+		samples = 200
+	}
+	return samples
+}
+
+// samples the video between start and stop, detects cuts and returns the
+// timestamps of numcaps screenshots spread over the most significant scenes
+func (g *generator) selectScenes(ctx context.Context, gens []*screengen.Generator, start, stop int64) ([]int64, error) {
+	samples := g.sceneSamples()
+	step := (stop - start) / int64(samples)
+	if step <= 0 {
+		return nil, fmt.Errorf("clip is too short to detect scenes")
+	}
+
+	g.log.Infof("scanning %d frames for scene changes", samples)
+	lumas := make([][]uint8, samples)
+	err := g.parallel(ctx, gens, samples, func(gen *screengen.Generator, i int) error {
+		ts := start + int64(i)*step + step/2
+		w, h := sceneSampleWidth, sceneSampleWidth
+		if g.media.Width > 0 && g.media.Height > 0 {
+			h = sceneSampleWidth * g.media.Height / g.media.Width
+		}
+		if h < 1 {
+			h = 1
+		}
+		img, err := gen.ImageWxH(ts, w, h)
+		if err != nil {
+			return fmt.Errorf("can't sample frame at %s: %v", formatTimestamp(ts), err)
+		}
+		lumas[i] = luma(img)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a cut at i lies between the samples i-1 and i
+	type cut struct {
+		idx   int
+		score float64
+	}
+	var cuts []cut
+	for i := 1; i < samples; i++ {
+		score := lumaDifference(lumas[i-1], lumas[i])
+		if score >= sceneCutThreshold {
+			cuts = append(cuts, cut{i, score})
+		}
+	}
+	g.log.Debugf("detected %d scene changes", len(cuts))
+
+	// keep the strongest cuts, they separate the most significant scenes
+	sort.Slice(cuts, func(a, b int) bool { return cuts[a].score > cuts[b].score })
+	if len(cuts) > g.numcaps-1 {
+		cuts = cuts[:g.numcaps-1]
+	}
+	bounds := []int{0, samples}
+	for _, c := range cuts {
+		bounds = append(bounds, c.idx)
+	}
+	sort.Ints(bounds)
+
+	// scenes as [from, to) in samples
+	scenes := make([][2]int, 0, g.numcaps)
+	for i := 1; i < len(bounds); i++ {
+		scenes = append(scenes, [2]int{bounds[i-1], bounds[i]})
+	}
+
+	// not enough scenes, split up the longest ones
+	for len(scenes) < g.numcaps {
+		longest := 0
+		for i, sc := range scenes {
+			if sc[1]-sc[0] > scenes[longest][1]-scenes[longest][0] {
+				longest = i
+			}
+		}
+		sc := scenes[longest]
+		if sc[1]-sc[0] < 2 {
+			break
+		}
+		mid := (sc[0] + sc[1]) / 2
+		scenes = append(scenes[:longest+1], scenes[longest:]...)
+		scenes[longest] = [2]int{sc[0], mid}
+		scenes[longest+1] = [2]int{mid, sc[1]}
+	}
+
+	stamps := make([]int64, len(scenes))
+	for i, sc := range scenes {
+		stamps[i] = start + int64(sc[0]+sc[1])*step/2
+		g.log.Debugf("scene %02d from %s to %s", i+1, formatTimestamp(start+int64(sc[0])*step), formatTimestamp(start+int64(sc[1])*step))
+	}
+	return stamps, nil
+}
+
+// returns the luma values of all pixels of img
+func luma(img image.Image) []uint8 {
+	grey := imaging.Grayscale(img)
+	l := make([]uint8, 0, len(grey.Pix)/4)
+	for i := 0; i < len(grey.Pix); i += 4 {
+		l = append(l, grey.Pix[i])
+	}
+	return l
+}
+
+// returns the mean absolute difference of two luma slices
+func lumaDifference(a, b []uint8) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var sum int
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return float64(sum) / float64(len(a))
+}
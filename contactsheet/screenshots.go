@@ -0,0 +1,861 @@
+package contactsheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/disintegration/gift"
+	"github.com/disintegration/imaging"
+	"gitlab.com/opennota/screengen"
+)
+
+// generates screenshots and returns a list of frames
+func (g *generator) generateScreenshots(ctx context.Context) ([]Frame, error) {
+	pool := g.newDecoderPool()
+	defer pool.close()
+	stamps, err := g.planScreenshots(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return g.extract(ctx, pool.get(len(stamps)), stamps)
+}
+
+// returns the timestamps screenshots are taken at and lays out the contact
+// sheet, decoding needs the final size of the screenshots
+func (g *generator) planScreenshots(ctx context.Context, pool *decoderPool) ([]int64, error) {
+	stamps, err := g.planTimestamps(ctx, pool)
+	if err != nil || g.opts.SingleImages {
+		return stamps, err
+	}
+
+	if g.opts.Layout == "hero" {
+		if g.featured, g.featuredBest, err = g.featuredPositions(len(stamps)); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.autoLayout(len(stamps)); err != nil {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// returns the timestamps screenshots are taken at, the decoders of pool are
+// used for detecting black bars, intro, credits and scenes
+func (g *generator) planTimestamps(ctx context.Context, pool *decoderPool) ([]int64, error) {
+	if g.opts.AutoCrop {
+		crop, err := g.detectCrop(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		g.media.Crop = crop
+	}
+
+	if len(g.opts.At) > 0 {
+		stamps, err := g.atTimestamps()
+		if err != nil {
+			return nil, err
+		}
+		g.numcaps = len(stamps)
+		g.start, g.end = 0, 1000*(g.media.Duration/1000)
+		return stamps, nil
+	}
+
+	if len(g.opts.Ranges) > 0 {
+		var err error
+		if g.ranges, err = g.parseRanges(); err != nil {
+			return nil, err
+		}
+		stamps, err := g.rangeTimestamps()
+		if err != nil {
+			return nil, err
+		}
+		g.start, g.end = g.ranges[0].start, g.ranges[len(g.ranges)-1].end
+		return stamps, nil
+	}
+
+	// truncate duration to full seconds
+	// this prevents empty/black images when the movie is some milliseconds longer
+	// ffmpeg then sometimes takes a black screenshot AFTER the movie finished for some reason
+	length := 1000 * (g.media.Duration / 1000)
+	from, err := parseDuration(g.opts.From, length)
+	if err != nil {
+		return nil, fmt.Errorf("from: %v", err)
+	}
+	end, err := parseDuration(g.opts.To, length)
+	if err != nil {
+		return nil, fmt.Errorf("to: %v", err)
+	}
+
+	if from < 0 {
+		g.log.Infof("from option is negative, starting %s before end of file", formatTimestamp(-from))
+		from = length + from
+		if from <= 0 {
+			return nil, fmt.Errorf("from: %s is before the start of the video", g.opts.From)
+		}
+	}
+	// an explicit from or to wins over the detected intro and credits
+	if g.opts.SkipIntro && from == 0 {
+		if from, err = g.detectIntro(ctx, pool, length); err != nil {
+			return nil, err
+		}
+		if from > 0 {
+			g.log.Infof("intro detected, content starts at %s", formatTimestamp(from))
+		}
+	}
+	if from > 0 {
+		g.log.Infof("First screenshot will be at %s", formatTimestamp(from))
+	}
+
+	duration := length
+	if g.opts.SkipCredits && end == 0 {
+		if duration, err = g.detectCredits(ctx, pool, length); err != nil {
+			return nil, err
+		}
+		if duration < length {
+			g.log.Infof("credits detected, content ends at %s", formatTimestamp(duration))
+		}
+	}
+
+	if end < 0 {
+		g.log.Infof("to option is negative, substracting %s from end of file", formatTimestamp(-end))
+		duration = duration + end
+	} else if end > 0 {
+		if end > length {
+			return nil, fmt.Errorf("to: %s is after the end of the video (%s)", g.opts.To, formatTimestamp(length))
+		}
+		g.log.Infof("Last screenshot will be at %s", formatTimestamp(end))
+		duration = end
+	}
+
+	if from >= duration {
+		return nil, fmt.Errorf("from (%s) has to be before to (%s)", formatTimestamp(from), formatTimestamp(duration))
+	}
+	g.start, g.end = from, duration
+	duration = duration - from
+
+	interval, err := parseDuration(g.opts.Interval, duration)
+	if err != nil {
+		return nil, fmt.Errorf("interval: %v", err)
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("interval: %s can't be negative", g.opts.Interval)
+	}
+	if interval > 0 {
+		if duration < interval {
+			return nil, errors.New("specified interval is longer than video duration, " +
+				"use smaller interval or set numcaps instead")
+		}
+		g.numcaps = int(duration / interval)
+		g.log.Debugf("interval option set, numcaps are set to %d", g.numcaps)
+		g.columns = int(math.Sqrt(float64(g.numcaps)))
+	}
+
+	if g.numcaps <= 0 {
+		return nil, fmt.Errorf("invalid number of captures: %d", g.numcaps)
+	}
+
+	inc := duration / (int64(g.numcaps))
+
+	if end != 0 && from > 0 && g.numcaps > 1 {
+		inc = duration / (int64(g.numcaps) - 1)
+	}
+
+	if inc <= 60000 {
+		g.log.Warn("very small timestamps in use... consider decreasing numcaps")
+	}
+	if inc <= 9000 {
+		g.log.Errorf("interval (%ds) is way to small (less then 9s), please decrease numcaps", inc/1000)
+	}
+
+	mode := g.opts.Select
+	if mode == "chapters" && len(g.media.Chapters) == 0 {
+		g.log.Warn("no chapters found, spreading screenshots evenly")
+		mode = "even"
+	}
+
+	var stamps []int64
+	switch mode {
+	case "", "even":
+	case "chapters":
+		stamps, g.titles = g.chapterTimestamps(from, from+duration)
+		if len(stamps) == 0 {
+			return nil, errors.New("no chapters between from and to")
+		}
+		g.numcaps = len(stamps)
+	case "scenes":
+		stamps, err = g.selectScenes(ctx, pool.get(g.sceneSamples()), from, from+duration)
+		if err != nil {
+			return nil, err
+		}
+		g.numcaps = len(stamps)
+	default:
+		return nil, fmt.Errorf("unknown frame selection mode: %s", g.opts.Select)
+	}
+
+	if stamps == nil {
+		d := inc
+
+		if interval > 0 {
+			d = interval
+		}
+
+		if from > 0 {
+			d = from
+		}
+
+		stamps = make([]int64, g.numcaps)
+		for i := range stamps {
+			stamps[i] = d
+			if interval > 0 {
+				d += interval
+			} else {
+				d += inc
+			}
+		}
+	}
+
+	return stamps, nil
+}
+
+// generates screenshots at the given timestamps, the prepared screenshots
+// are returned in the same order
+func (g *generator) extract(ctx context.Context, gens []*screengen.Generator, stamps []int64) ([]Frame, error) {
+	g.log.Debugf("extracting %d screenshots with %d workers", len(stamps), len(gens))
+	// retries move the timestamps, the windows are based on the planned ones
+	targets := append([]int64(nil), stamps...)
+	window := func(i int) retryWindow {
+		return g.retryWindow(targets, i)
+	}
+	g.tile = g.tileSize(g.frameBounds())
+	thumbnails := make([]Frame, len(stamps))
+
+	// without sequential rejectors or best featured screenshots every frame
+	// is processed right away, otherwise all of them are kept until they
+	// are checked, but only at their size on the contact sheet
+	if !g.hasRejectors(true) && g.featuredBest == 0 {
+		err := g.parallel(ctx, gens, len(stamps), func(gen *screengen.Generator, i int) error {
+			img, stamp, err := g.capture(gen, stamps[i], window(i))
+			if err != nil {
+				return err
+			}
+			stamps[i] = stamp
+			thumbnails[i] = g.process(i, img, stamp, g.detectNudity(img))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		images := make([]image.Image, len(stamps))
+		// nudity is detected in the decoded frames, before they are shrunk
+		nudities := make([]nudity, len(stamps))
+		err := g.parallel(ctx, gens, len(stamps), func(gen *screengen.Generator, i int) error {
+			img, stamp, err := g.capture(gen, stamps[i], window(i))
+			if err != nil {
+				return err
+			}
+			nudities[i] = g.detectNudity(img)
+			images[i], stamps[i] = g.shrink(img, window(i).hero), stamp
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if g.hasRejectors(true) {
+			// has to run in order, every frame is compared to the ones before it
+			if err := g.rejectSequential(ctx, gens[0], images, stamps, nudities, window); err != nil {
+				return nil, err
+			}
+		}
+
+		if g.featuredBest > 0 {
+			scores := make([]float64, len(images))
+			for i, img := range images {
+				scores[i] = g.quality(img)
+			}
+			g.featureBest(scores)
+		}
+
+		err = g.parallel(ctx, gens, len(stamps), func(gen *screengen.Generator, i int) error {
+			thumbnails[i] = g.process(i, images[i], stamps[i], nudities[i])
+			images[i] = nil
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// every screenshot stands for the part of the video since the previous
+	// one, the first one of a range for the start of its range
+	for i := range thumbnails {
+		if i > 0 {
+			thumbnails[i].Start = stamps[i-1]
+		}
+		if r, ok := g.rangeOf(stamps[i]); ok && (i == 0 || stamps[i-1] < r.start) {
+			thumbnails[i].Start = r.start
+		}
+	}
+
+	return thumbnails, nil
+}
+
+// the decoders of a Generate call, every worker needs its own decoder as
+// seeking changes its state. They are opened on first use and shared by
+// all steps.
+type decoderPool struct {
+	g    *generator
+	gens []*screengen.Generator
+}
+
+// returns a pool which starts with the probed decoder
+func (g *generator) newDecoderPool() *decoderPool {
+	return &decoderPool{g: g, gens: []*screengen.Generator{g.media.gen}}
+}
+
+// returns the decoders for n jobs, up to one per worker, opening more of
+// them if needed
+func (p *decoderPool) get(n int) []*screengen.Generator {
+	workers := p.g.opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	for len(p.gens) < workers {
+		wgen, err := screengen.NewGenerator(p.g.fn)
+		if err != nil {
+			p.g.log.Warnf("unable to open another decoder, using %d workers: %v", len(p.gens), err)
+			break
+		}
+		wgen.Fast = p.g.opts.Fast
+		p.gens = append(p.gens, wgen)
+	}
+	if workers > len(p.gens) {
+		workers = len(p.gens)
+	}
+	return p.gens[:workers]
+}
+
+// closes all decoders except the probed one, which is closed together with
+// the media
+func (p *decoderPool) close() {
+	for _, gen := range p.gens[1:] {
+		gen.Close()
+	}
+}
+
+// parses the explicit timestamps of Options.At, the returned timestamps are
+// sorted and free of duplicates
+func (g *generator) atTimestamps() ([]int64, error) {
+	duration := 1000 * (g.media.Duration / 1000)
+	var stamps []int64
+	for _, at := range g.opts.At {
+		stamp, err := parseDuration(at, duration)
+		if err != nil {
+			return nil, err
+		}
+		if stamp < 0 {
+			// negative timestamps are relative to the end
+			stamp = duration + stamp
+			if stamp < 0 {
+				return nil, fmt.Errorf("timestamp %s is before the start of the video", at)
+			}
+		}
+		if stamp > duration {
+			return nil, fmt.Errorf("timestamp %s is after the end of the video (%s)", at, formatTimestamp(duration))
+		}
+		stamps = append(stamps, stamp)
+	}
+	sort.Slice(stamps, func(i, j int) bool { return stamps[i] < stamps[j] })
+
+	unique := stamps[:0]
+	for i, stamp := range stamps {
+		if i == 0 || stamp != stamps[i-1] {
+			unique = append(unique, stamp)
+		}
+	}
+	g.log.Debugf("using %d explicit timestamps", len(unique))
+	return unique, nil
+}
+
+// calls fn for every index from 0 to n-1, spread over one worker per
+// decoder. The first error stops all workers and is returned.
+func (g *generator) parallel(ctx context.Context, gens []*screengen.Generator, n int, fn func(gen *screengen.Generator, i int) error) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	errs := make(chan error, len(gens))
+	var wg sync.WaitGroup
+
+	for _, wgen := range gens {
+		wg.Add(1)
+		go func(gen *screengen.Generator) {
+			defer wg.Done()
+			// panics can't be recovered by Generate as they happen in another goroutine
+			defer func() {
+				if r := recover(); r != nil {
+					errs <- fmt.Errorf("panic while processing %s: %v", g.fn, r)
+					cancel()
+				}
+			}()
+			for i := range jobs {
+				if err := fn(gen, i); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}(wgen)
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-wctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// the range retries of a screenshot have to stay in (exclusive) and the
+// spacing to its neighbours percentages of the retry step are based on
+type retryWindow struct {
+	lo, hi  int64
+	spacing int64
+	hero    bool // the screenshot may be featured, FastScale decodes it at the hero size
+}
+
+// returns the retry window of the i-th of the planned timestamps. Retries
+// must not pass the neighbours to keep the order, if they or the candidates
+// can move in both directions they meet halfway.
+func (g *generator) retryWindow(targets []int64, i int) retryWindow {
+	w := retryWindow{lo: g.start - 1, hi: g.end}
+	w.hero = g.featured[i] || g.featuredBest > 0
+	around := g.opts.RetryDirection == "alternate" || g.opts.Candidates > 1
+	if i > 0 {
+		w.lo = targets[i-1]
+		if around {
+			w.lo = (targets[i-1] + targets[i]) / 2
+		}
+	}
+	if i < len(targets)-1 {
+		w.hi = targets[i+1]
+		if around {
+			w.hi = (targets[i] + targets[i+1]) / 2
+		}
+	}
+
+	// retries stay in the range of the screenshot
+	if r, ok := g.rangeOf(targets[i]); ok {
+		if w.lo < r.start-1 {
+			w.lo = r.start - 1
+		}
+		if w.hi > r.end {
+			w.hi = r.end
+		}
+	}
+
+	switch {
+	case i < len(targets)-1:
+		w.spacing = targets[i+1] - targets[i]
+	case i > 0:
+		w.spacing = targets[i] - targets[i-1]
+	default:
+		w.spacing = w.hi - w.lo
+	}
+	return w
+}
+
+// takes the screenshot at d and retries within w if the frame should be
+// skipped. Returns the frame and its actual timestamp.
+func (g *generator) capture(gen *screengen.Generator, d int64, w retryWindow) (image.Image, int64, error) {
+	if g.opts.Candidates > 1 {
+		return g.bestCandidate(gen, d, w)
+	}
+
+	img, err := g.decode(gen, d, w.hero)
+	if err != nil {
+		return nil, d, fmt.Errorf("can't generate screenshot: %v", err)
+	}
+
+	// should we skip any images?
+	if g.hasRejectors(false) {
+		return g.retry(gen, img, d, w, g.skipImage)
+	}
+	return img, d, nil
+}
+
+// seeks away from d up to Options.Retries times as long as reject returns
+// true for the frame img taken at d, retries never leave the window w. If
+// every frame is rejected the last one or, with the "best" fallback, the
+// one with the highest quality is returned.
+func (g *generator) retry(gen *screengen.Generator, img image.Image, d int64, w retryWindow, reject func(image.Image, int64) bool) (image.Image, int64, error) {
+	// the step was validated by Generate, 10 seconds is used if it's empty
+	step, _ := parseDuration(g.opts.RetryStep, w.spacing)
+	if step <= 0 {
+		step = 10000
+	}
+	best := g.opts.RetryFallback == "best"
+
+	stamp := d
+	bestImg, bestStamp, bestScore := img, d, 0.0
+	if best {
+		bestScore = g.quality(img)
+	}
+	maxCount := g.opts.Retries
+	count := 1
+	outBefore, outAfter := false, false
+	rejected := reject(img, stamp)
+	for n := 1; rejected && maxCount >= count; n++ {
+		next := g.retryStamp(d, step, n)
+		if next <= w.lo || next >= w.hi {
+			if next < d {
+				outBefore = true
+			} else {
+				outAfter = true
+			}
+			// alternating retries can continue on the other side
+			if g.opts.RetryDirection == "alternate" && !(outBefore && outAfter) {
+				continue
+			}
+			g.log.Errorf("[%d/%d] no more frames can be skipped at: %s, next screenshot or end of clip reached", count, maxCount, formatTimestamp(stamp))
+			break
+		}
+		g.log.Warnf("[%d/%d] frame skipped based on settings at: %s retry at: %s", count, maxCount, formatTimestamp(stamp), formatTimestamp(next))
+		stamp = next
+		var err error
+		img, err = g.decode(gen, stamp, w.hero)
+		if err != nil {
+			return nil, stamp, fmt.Errorf("can't generate screenshot at %s: %v", formatTimestamp(stamp), err)
+		}
+		rejected = reject(img, stamp)
+		if best && rejected {
+			if score := g.quality(img); score > bestScore {
+				bestImg, bestStamp, bestScore = img, stamp, score
+			}
+		}
+		count = count + 1
+	}
+
+	if best && rejected && bestStamp != stamp {
+		g.log.Warnf("no frame accepted, using the best candidate at %s", formatTimestamp(bestStamp))
+		return bestImg, bestStamp, nil
+	}
+	return img, stamp, nil
+}
+
+// returns the timestamp of the n-th retry for a screenshot at d
+func (g *generator) retryStamp(d, step int64, n int) int64 {
+	switch g.opts.RetryDirection {
+	case "backward":
+		return d - int64(n)*step
+	case "alternate":
+		return aroundStamp(d, step, n)
+	}
+	return d + int64(n)*step
+}
+
+// returns the n-th timestamp alternating around d: d, d+step, d-step,
+// d+2*step, ...
+func aroundStamp(d, step int64, n int) int64 {
+	k := int64(n+1) / 2
+	if n%2 == 0 {
+		return d - k*step
+	}
+	return d + k*step
+}
+
+// takes Options.Candidates frames around d within w and returns the one
+// with the highest quality, frames skipped by the settings are only used if
+// all of them are
+func (g *generator) bestCandidate(gen *screengen.Generator, d int64, w retryWindow) (image.Image, int64, error) {
+	// the step was validated by Generate, 1 second is used if it's empty
+	step, _ := parseDuration(g.opts.CandidateStep, w.spacing)
+	if step <= 0 {
+		step = 1000
+	}
+	skip := g.hasRejectors(false)
+
+	var bestImg image.Image
+	bestStamp, bestScore, bestSkipped := d, 0.0, false
+	outBefore, outAfter := false, false
+	for n, taken := 0, 0; taken < g.opts.Candidates && !(outBefore && outAfter); n++ {
+		stamp := aroundStamp(d, step, n)
+		// the planned timestamp is always taken
+		if n > 0 && (stamp <= w.lo || stamp >= w.hi) {
+			if stamp < d {
+				outBefore = true
+			} else {
+				outAfter = true
+			}
+			continue
+		}
+
+		img, err := g.decode(gen, stamp, w.hero)
+		if err != nil {
+			return nil, stamp, fmt.Errorf("can't generate screenshot at %s: %v", formatTimestamp(stamp), err)
+		}
+		taken++
+
+		skipped := skip && g.skipImage(img, stamp)
+		score := g.quality(img)
+		g.log.Debugf("candidate %d at %s has quality %.3f", taken, formatTimestamp(stamp), score)
+		if bestImg == nil || (bestSkipped && !skipped) || (skipped == bestSkipped && score > bestScore) {
+			bestImg, bestStamp, bestScore, bestSkipped = img, stamp, score, skipped
+		}
+	}
+
+	if bestSkipped {
+		g.log.Warnf("all candidates around %s are skipped based on settings, using the best one at %s", formatTimestamp(d), formatTimestamp(bestStamp))
+	}
+	return bestImg, bestStamp, nil
+}
+
+// checks the frames with the sequential rejectors (like duplicate) in
+// order of their timestamps and replaces rejected ones by retrying, the
+// same way frames are skipped by skipImage. The nudity of replaced frames
+// is detected again.
+func (g *generator) rejectSequential(ctx context.Context, gen *screengen.Generator, images []image.Image, stamps []int64, nudities []nudity, window func(int) retryWindow) error {
+	reject := func(img image.Image, ts int64) bool {
+		return g.skipSequential(img, ts) || g.skipImage(img, ts)
+	}
+	for i := range images {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// the frame itself already passed skipImage, so retry only checks
+		// it with the sequential rejectors
+		first := true
+		img, stamp, err := g.retry(gen, images[i], stamps[i], window(i), func(img image.Image, ts int64) bool {
+			if first {
+				first = false
+				return g.skipSequential(img, ts)
+			}
+			return reject(img, ts)
+		})
+		if err != nil {
+			return err
+		}
+		if stamp != stamps[i] {
+			nudities[i] = g.detectNudity(img)
+		}
+		images[i], stamps[i] = g.shrink(img, window(i).hero), stamp
+		g.accept(images[i])
+	}
+	return nil
+}
+
+// applies the censoring, all filters, timestamps and watermarks to the
+// i-th screenshot, n is the nudity detected in its decoded frame
+func (g *generator) process(i int, img image.Image, stamp int64, n nudity) Frame {
+	g.log.Infof("generating screenshot %02d/%02d at %s", i+1, g.numcaps, formatTimestampMS(stamp))
+	timestamp := g.formatLabel(stamp)
+	var title string
+	if i < len(g.titles) && g.titles[i] != "" {
+		title = g.titles[i]
+		timestamp = fmt.Sprintf("%s - %s", timestamp, title)
+	}
+	disableTimestamps := g.opts.DisableTimestamps
+	//var thumb image.Image
+	if g.featured[i] {
+		hero := g.heroSize(g.tile)
+		img = imaging.Fill(img, hero.X, hero.Y, imaging.Center, imaging.Lanczos)
+	} else if g.opts.Width > 0 {
+		img = imaging.Resize(img, g.opts.Width, 0, imaging.Lanczos)
+	} else if g.opts.Width == 0 && g.opts.Height > 0 {
+		img = imaging.Resize(img, 0, g.opts.Height, imaging.Lanczos)
+	}
+
+	var censored bool
+	if img, censored = g.censor(img, n); censored {
+		g.log.Warnf("screenshot %02d at %s censored by nudity detection", i+1, formatTimestamp(stamp))
+	}
+
+	//apply filters
+	for _, filter := range g.opts.Filters {
+		switch filter {
+		case "greyscale":
+			img = imaging.Grayscale(img)
+			img = imaging.Sharpen(img, 1.0)
+			img = imaging.AdjustContrast(img, 20)
+			g.log.Debug("greyscale filter applied")
+		case "invert":
+			img = imaging.Invert(img)
+			g.log.Debug("invert filter applied")
+		case "fancy":
+			//TODO: find a way to do this without GIFT...
+			g.log.Debug("fancy filter applied")
+			//draw timestamp to the image before rotating it!
+			tsimage := g.drawTimestamp(timestamp)
+			img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), g.opts.TimestampOpacity)
+
+			gf := gift.New(
+				gift.Rotate(randomInt(g.random(i), g.opts.FancyMinAngle, g.opts.FancyMaxAngle), g.opts.BgContent, gift.CubicInterpolation),
+			)
+			dst := image.NewRGBA(gf.Bounds(img.Bounds()))
+			gf.Draw(dst, img)
+			img = dst
+			disableTimestamps = true
+		case "sepia":
+			g.log.Debug("sepia filter applied")
+			gf := gift.New(
+				gift.Sepia(100),
+			)
+			dst := image.NewRGBA(gf.Bounds(img.Bounds()))
+			gf.Draw(dst, img)
+			img = dst
+		case "cross":
+			g.log.Debug("cross filter applied")
+			img = crossProcessingFilter(img, 0.5, 9)
+		case "strip":
+			g.log.Debug("image stip filter applied")
+			//draw timestamp!
+			tsimage := g.drawTimestamp(timestamp)
+			img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), g.opts.TimestampOpacity)
+			disableTimestamps = true
+			img = imageStripFilter(img)
+		}
+	}
+
+	if !disableTimestamps && !g.opts.SingleImages {
+		g.log.Debug("adding timestamp to image")
+		tsimage := g.drawTimestamp(timestamp)
+		img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), g.opts.TimestampOpacity)
+	}
+
+	//watermark middle image
+	if i == (g.numcaps-1)/2 && g.opts.Watermark != "" && !g.opts.SingleImages {
+		ov, err := imaging.Open(g.opts.Watermark)
+		if err == nil {
+			if ov.Bounds().Dx() > img.Bounds().Dx() {
+				ov = imaging.Resize(ov, img.Bounds().Dx(), 0, imaging.Lanczos)
+			}
+			if ov.Bounds().Dy() > img.Bounds().Dy() {
+				ov = imaging.Resize(ov, 0, img.Bounds().Dy(), imaging.Lanczos)
+			}
+			posX := (img.Bounds().Dx() - ov.Bounds().Dx()) / 2
+			posY := (img.Bounds().Dy() - ov.Bounds().Dy()) / 2
+			img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
+		}
+	} else if g.opts.Watermark != "" && g.opts.SingleImages {
+		ov, err := imaging.Open(g.opts.Watermark)
+		if err == nil {
+			if ov.Bounds().Dx() > img.Bounds().Dx() {
+				ov = imaging.Resize(ov, img.Bounds().Dx(), 0, imaging.Lanczos)
+			}
+			if ov.Bounds().Dy() > img.Bounds().Dy() {
+				ov = imaging.Resize(ov, 0, img.Bounds().Dy(), imaging.Lanczos)
+			}
+			posX := (img.Bounds().Dx() - ov.Bounds().Dx()) / 2
+			posY := (img.Bounds().Dy() - ov.Bounds().Dy()) / 2
+			img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
+		}
+	}
+
+	if g.opts.WatermarkAll != "" {
+		ov, err := imaging.Open(g.opts.WatermarkAll)
+		if err == nil {
+			if ov.Bounds().Dx() > (img.Bounds().Dx() / 4) {
+				ov = imaging.Resize(ov, (img.Bounds().Dx() / 4), 0, imaging.Lanczos)
+			}
+			if ov.Bounds().Dy() > (img.Bounds().Dy() / 4) {
+				ov = imaging.Resize(ov, 0, (img.Bounds().Dy() / 4), imaging.Lanczos)
+			}
+			//default position for watermarking is bottom-left
+			posX := 10
+			posY := img.Bounds().Dy() - ov.Bounds().Dy() - 10
+			img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
+		}
+	}
+
+	return Frame{Image: img, Timestamp: stamp, Chapter: title, Bounds: img.Bounds(), Censored: censored}
+}
+
+// decodes the frame at ts, if FastScale is set the decoder already scales
+// it down close to the final size so only a small image is resized later.
+// hero frames are scaled for the size of a featured screenshot.
+func (g *generator) decode(gen *screengen.Generator, ts int64, hero bool) (image.Image, error) {
+	start := time.Now()
+	var img image.Image
+	var err error
+	if g.opts.FastScale {
+		w, h := g.decodeSize(hero)
+		img, err = gen.ImageWxH(ts, w, h)
+	} else {
+		img, err = gen.Image(ts)
+	}
+	if err == nil {
+		img = g.cropFrame(img)
+		g.log.Debugf("decoded %dx%d frame at %s in %v", img.Bounds().Dx(), img.Bounds().Dy(), formatTimestamp(ts), time.Since(start))
+	}
+	return img, err
+}
+
+// returns the size frames are decoded at if FastScale is set, twice the
+// size of a (featured) screenshot to leave some room for the final Lanczos
+// resize
+func (g *generator) decodeSize(hero bool) (int, int) {
+	w, h := g.media.Width, g.media.Height
+	if w <= 0 || h <= 0 {
+		return w, h
+	}
+	width, height := g.opts.Width, g.opts.Height
+	if hero {
+		width, height = g.heroSize(g.tileSize(g.frameBounds())).X, 0
+	}
+	if width > 0 && 2*width < w {
+		h = h * 2 * width / w
+		w = 2 * width
+	} else if width == 0 && height > 0 && 2*height < h {
+		w = w * 2 * height / h
+		h = 2 * height
+	}
+	return w, h
+}
+
+// returns the size of the decoded frames after cropping black bars
+func (g *generator) frameBounds() image.Rectangle {
+	if !g.media.Crop.Empty() {
+		return image.Rect(0, 0, g.media.Crop.Dx(), g.media.Crop.Dy())
+	}
+	return image.Rect(0, 0, g.media.Width, g.media.Height)
+}
+
+// scales img down to the width of a (featured) screenshot, so frames which
+// have to be kept until all of them are taken don't use more memory than
+// needed
+func (g *generator) shrink(img image.Image, hero bool) image.Image {
+	size := g.tile
+	if hero {
+		size = g.heroSize(g.tile)
+	}
+	if size.X <= 0 || img.Bounds().Dx() <= size.X {
+		return img
+	}
+	return imaging.Resize(img, size.X, 0, imaging.Lanczos)
+}
@@ -0,0 +1,92 @@
+package contactsheet
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRetryWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		direction string
+		ranges    []string
+		at        []string
+		stamps    []int64
+		i         int
+		want      retryWindow
+	}{
+		{name: "first", stamps: []int64{150000, 300000, 450000, 600000}, i: 0, want: retryWindow{lo: -1, hi: 300000, spacing: 150000}},
+		{name: "middle", stamps: []int64{150000, 300000, 450000, 600000}, i: 1, want: retryWindow{lo: 150000, hi: 450000, spacing: 150000}},
+		{name: "last", stamps: []int64{150000, 300000, 450000, 600000}, i: 3, want: retryWindow{lo: 450000, hi: 600000, spacing: 150000}},
+		// retries of the last screenshot must not pass to
+		{name: "to", to: "5:00", stamps: []int64{75000, 150000, 225000, 300000}, i: 3, want: retryWindow{lo: 225000, hi: 300000, spacing: 75000}},
+		{name: "to negative", to: "-5:00", stamps: []int64{75000, 150000, 225000, 300000}, i: 3, want: retryWindow{lo: 225000, hi: 300000, spacing: 75000}},
+		{name: "from", from: "1:00", stamps: []int64{60000, 195000, 330000, 465000}, i: 0, want: retryWindow{lo: 59999, hi: 195000, spacing: 135000}},
+		{name: "alternate first", from: "1:00", to: "5:00", direction: "alternate", stamps: []int64{60000, 140000, 220000, 300000}, i: 0, want: retryWindow{lo: 59999, hi: 100000, spacing: 80000}},
+		{name: "alternate last", from: "1:00", to: "5:00", direction: "alternate", stamps: []int64{60000, 140000, 220000, 300000}, i: 3, want: retryWindow{lo: 260000, hi: 300000, spacing: 80000}},
+		// retries stay in the range of the screenshot
+		{name: "end of a range", ranges: []string{"1:00-2:00", "5:00-6:00"}, stamps: []int64{75000, 105000, 315000, 345000}, i: 1, want: retryWindow{lo: 75000, hi: 120000, spacing: 210000}},
+		{name: "start of a range", ranges: []string{"1:00-2:00", "5:00-6:00"}, stamps: []int64{75000, 105000, 315000, 345000}, i: 2, want: retryWindow{lo: 299999, hi: 345000, spacing: 30000}},
+		{name: "last range", ranges: []string{"1:00-2:00", "5:00-6:00"}, stamps: []int64{75000, 105000, 315000, 345000}, i: 3, want: retryWindow{lo: 315000, hi: 360000, spacing: 30000}},
+		{name: "at", at: []string{"1:00", "9:00"}, stamps: []int64{60000, 540000}, i: 1, want: retryWindow{lo: 60000, hi: 600000, spacing: 480000}},
+		{name: "single at", at: []string{"1:00"}, stamps: []int64{60000}, i: 0, want: retryWindow{lo: -1, hi: 600000, spacing: 600001}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.from != "" {
+				opts.From = tt.from
+			}
+			if tt.to != "" {
+				opts.To = tt.to
+			}
+			if tt.direction != "" {
+				opts.RetryDirection = tt.direction
+			}
+			opts.Ranges, opts.At = tt.ranges, tt.at
+			// the duration is truncated to full seconds
+			g := testGenerator(t, opts, 600500, 1920, 1080)
+			stamps, err := g.planTimestamps(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stamps, tt.stamps) {
+				t.Fatalf("planned %v, want %v", stamps, tt.stamps)
+			}
+			if got := g.retryWindow(stamps, tt.i); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryStamp(t *testing.T) {
+	tests := []struct {
+		direction string
+		want      []int64 // retries 1 to 4
+	}{
+		{"forward", []int64{110000, 120000, 130000, 140000}},
+		{"backward", []int64{90000, 80000, 70000, 60000}},
+		{"alternate", []int64{110000, 90000, 120000, 80000}},
+	}
+	for _, tt := range tests {
+		g := &generator{}
+		g.opts.RetryDirection = tt.direction
+		for n, want := range tt.want {
+			if got := g.retryStamp(100000, 10000, n+1); got != want {
+				t.Errorf("%s: retry %d at %d, want %d", tt.direction, n+1, got, want)
+			}
+		}
+	}
+}
+
+func TestAroundStamp(t *testing.T) {
+	want := []int64{1000, 1100, 900, 1200, 800, 1300}
+	for n, w := range want {
+		if got := aroundStamp(1000, 100, n); got != w {
+			t.Errorf("aroundStamp(1000, 100, %d) = %d, want %d", n, got, w)
+		}
+	}
+}
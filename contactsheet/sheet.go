@@ -0,0 +1,203 @@
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/BurntSushi/freetype-go/freetype"
+	"github.com/disintegration/imaging"
+	"github.com/dustin/go-humanize"
+)
+
+// gets the timestamp value ("HH:MM:SS") and returns an image
+// TODO: rework this to take any string and a bool for full width/centered text
+func (g *generator) drawTimestamp(timestamp string) image.Image {
+	// an empty image is returned on errors, so a missing timestamp won't abort the generation
+	timestamped := image.NewRGBA(image.Rect(0, 0, 0, 0))
+
+	if g.font == nil {
+		return timestamped
+	}
+
+	fg, bg := image.White, image.Black
+	c := freetype.NewContext()
+	c.SetDPI(72)
+	c.SetFont(g.font)
+	c.SetFontSize(float64(g.opts.FontSize))
+
+	// get width and height of the string and draw an image to hold it
+	x, y, _ := c.MeasureString(timestamp)
+	rgba := image.NewRGBA(image.Rect(0, 0, (int(x)/256)+10, (int(y)/256)+10))
+	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
+	c.SetClip(rgba.Bounds())
+	c.SetDst(rgba)
+	c.SetSrc(fg)
+
+	//draw the text with 5px padding
+	pt := freetype.Pt(5, 3+int(c.PointToFix32(float64(g.opts.FontSize))>>8))
+	_, err := c.DrawString(timestamp, pt)
+	if err != nil {
+		g.log.Errorf("error creating timestamp image for: %s", timestamp)
+		return timestamped
+	}
+
+	g.log.Debugf("created timestamp image for: %s", timestamp)
+
+	return rgba
+
+}
+
+// composes the frames into a single image and updates their bounds to
+// the position on the contact sheet
+func (g *generator) makeContactSheet(thumbs []Frame) (image.Image, error) {
+	g.log.Info("Composing Contact Sheet")
+	// the tile size is taken from a regular screenshot, filters like fancy
+	// may change it
+	tile := g.tile
+	for idx, thumb := range thumbs {
+		if !g.featured[idx] {
+			tile = thumb.Image.Bounds().Size()
+			break
+		}
+	}
+	rects, size := g.layout(len(thumbs), g.featured, tile)
+
+	g.log.Debugf("single image dimension: %dx%d", tile.X, tile.Y)
+	g.log.Debugf("new image dimension: %dx%d", size.X, size.Y)
+
+	// create a new blank image
+	bgColor := g.opts.BgContent
+	dst := imaging.New(size.X, size.Y, bgColor)
+	var head image.Image
+	headerHeight := 0
+
+	if g.opts.Header {
+		g.log.Info("creating header information")
+		var err error
+		head, err = g.appendHeader(dst, thumbs)
+		if err != nil {
+			return nil, err
+		}
+		if head != nil {
+			headerHeight = head.Bounds().Dy()
+		}
+	}
+
+	// paste thumbnails into the new image side by side with padding if enabled
+	for idx, thumb := range thumbs {
+		dst = imaging.Paste(dst, thumb.Image, rects[idx].Min)
+		thumbs[idx].Bounds = rects[idx].Add(image.Pt(0, headerHeight))
+	}
+
+	if head != nil {
+		newIm := imaging.New(dst.Bounds().Dx(), dst.Bounds().Dy()+head.Bounds().Dy(), bgColor)
+		dst = imaging.Paste(newIm, dst, image.Pt(0, head.Bounds().Dy()))
+		dst = imaging.Paste(dst, head, image.Pt(0, 0))
+	}
+
+	return dst, nil
+}
+
+// creates the header image, returns nil if no font is available
+func (g *generator) appendHeader(im image.Image, thumbs []Frame) (image.Image, error) {
+	if g.font == nil {
+		return nil, nil
+	}
+
+	fontcolor, bg := image.NewUniform(g.opts.FgHeader), image.NewUniform(g.opts.BgHeader)
+	c := freetype.NewContext()
+	c.SetDPI(96)
+	c.SetFont(g.font)
+	c.SetFontSize(float64(g.opts.FontSize))
+
+	// get width and height of the string and draw an image to hold it
+	//x, y, _ := c.MeasureString(timestamp)
+	header := g.createHeader(thumbs)
+
+	rgba := image.NewNRGBA(image.Rect(0, 0, im.Bounds().Dx(), (5+int(c.PointToFix32(float64(g.opts.FontSize+4))>>8)*len(header))+10))
+	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
+	if g.opts.HeaderImage != "" {
+		ov, err := imaging.Open(g.opts.HeaderImage)
+		if err == nil {
+			if ov.Bounds().Dy() >= (rgba.Bounds().Dy() - 20) {
+				ov = imaging.Resize(ov, 0, rgba.Bounds().Dy()-20, imaging.Lanczos)
+			}
+			//center image inside header
+			posY := (rgba.Bounds().Dy() - ov.Bounds().Dy()) / 2
+			if posY < 10 {
+				posY = 10
+			}
+			rgba = imaging.Overlay(rgba, ov, image.Pt(rgba.Bounds().Dx()-ov.Bounds().Dx()-10, posY), 1.0)
+
+		} else {
+			g.log.Error("error opening header overlay image")
+		}
+	}
+
+	c.SetClip(rgba.Bounds())
+	c.SetDst(rgba)
+	c.SetSrc(fontcolor)
+
+	for i, s := range header {
+		//draw the text with 10px padding and lineheight +4
+		pt := freetype.Pt(10, (5 + int(c.PointToFix32(float64(g.opts.FontSize+4))>>8)*(i+1)))
+		_, err := c.DrawString(s, pt)
+		if err != nil {
+			return nil, fmt.Errorf("error drawing header: %v", err)
+		}
+	}
+
+	return rgba, nil
+}
+
+func (g *generator) createHeader(thumbs []Frame) []string {
+
+	var header []string
+	m := g.media
+
+	fsize := "unknown" // too expensive to download the whole file
+	if m.Size >= 0 {
+		fsize = humanize.IBytes(uint64(m.Size))
+	}
+
+	header = append(header, fmt.Sprintf("File Name: %s", m.Filename))
+	header = append(header, fmt.Sprintf("File Size: %s", fsize))
+	header = append(header, fmt.Sprintf("Duration: %s", formatTimestamp(m.Duration)))
+	if m.Crop.Empty() || (m.Crop.Dx() == m.Width && m.Crop.Dy() == m.Height) {
+		header = append(header, fmt.Sprintf("Resolution: %dx%d", m.Width, m.Height))
+	} else {
+		header = append(header, fmt.Sprintf("Resolution: %dx%d (active %dx%d)", m.Width, m.Height, m.Crop.Dx(), m.Crop.Dy()))
+	}
+	if len(m.Chapters) > 0 {
+		header = append(header, fmt.Sprintf("Chapters: %d", len(m.Chapters)))
+	}
+	if len(g.ranges) > 0 {
+		ranges := make([]string, len(g.ranges))
+		for i, r := range g.ranges {
+			ranges[i] = r.String()
+		}
+		header = append(header, fmt.Sprintf("Ranges: %s", strings.Join(ranges, ", ")))
+	}
+	var censored []string
+	for _, t := range thumbs {
+		if t.Censored {
+			censored = append(censored, formatTimestamp(t.Timestamp))
+		}
+	}
+	if len(censored) > 0 {
+		header = append(header, fmt.Sprintf("Censored: %s", strings.Join(censored, ", ")))
+	}
+
+	if g.opts.HeaderMeta {
+		header = append(header, fmt.Sprintf("FPS: %.2f, Bitrate: %dKbp/s", m.FPS, m.Bitrate))
+		header = append(header, fmt.Sprintf("Codec: %s / %s", m.VideoCodecLongName, m.AudioCodecLongName))
+	}
+
+	if g.opts.Comment != "" {
+		header = append(header, g.opts.Comment)
+	}
+
+	return header
+}
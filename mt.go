@@ -1,557 +1,220 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"image"
-	"image/draw"
 	"io/ioutil"
-	"math"
-	"mime"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
-	"time"
+	"sync"
+	"sync/atomic"
 
-	"github.com/BurntSushi/freetype-go/freetype"
-	"github.com/disintegration/gift"
 	"github.com/disintegration/imaging"
-	"github.com/dustin/go-humanize"
+	"github.com/mutschler/mt/contactsheet"
 	log "github.com/sirupsen/logrus"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"gitlab.com/opennota/screengen"
 )
 
 var GitVersion = ""
 var FfmpegVersion = ""
 var BuildTimestamp = ""
 
-var blankPixels int
-var allPixels int
-var mpath string
-var fontBytes []byte
 var version string = GitVersion + " (" + FfmpegVersion + ") built on " + BuildTimestamp
-var timestamps []string
-var numcaps int
 
-//gets the timestamp value ("HH:MM:SS") and returns an image
-//TODO: rework this to take any string and a bool for full width/centered text
-func drawTimestamp(timestamp string) image.Image {
-	var timestamped image.Image
-
-	font, err := freetype.ParseFont(fontBytes)
-	if err != nil {
-		log.Error(err)
-		return timestamped
-	}
-
-	fg, bg := image.White, image.Black
-	c := freetype.NewContext()
-	c.SetDPI(72)
-	c.SetFont(font)
-	c.SetFontSize(float64(viper.GetInt("font_size")))
-
-	// get width and height of the string and draw an image to hold it
-	x, y, _ := c.MeasureString(timestamp)
-	rgba := image.NewRGBA(image.Rect(0, 0, (int(x)/256)+10, (int(y)/256)+10))
-	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
-	c.SetSrc(fg)
-
-	//draw the text with 5px padding
-	pt := freetype.Pt(5, 3+int(c.PointToFix32(float64(viper.GetInt("font_size")))>>8))
-	_, err = c.DrawString(timestamp, pt)
-	if err != nil {
-		log.Errorf("error creating timestamp image for: %s", timestamp)
-		return timestamped
-	}
-
-	log.Debugf("created timestamp image for: %s", timestamp)
-
-	return rgba
+// exit codes used if generating a contact sheet failed for any input
+const (
+	exitSomeFailed = 2
+	exitAllFailed  = 3
+)
 
+// maps the current settings onto the options used by contactsheet
+func getOptions() contactsheet.Options {
+	opts := contactsheet.DefaultOptions()
+	opts.Numcaps = viper.GetInt("numcaps")
+	opts.Columns = viper.GetInt("columns")
+	opts.Padding = viper.GetInt("padding")
+	opts.Width = viper.GetInt("width")
+	opts.Height = viper.GetInt("height")
+	opts.SheetWidth = viper.GetInt("sheet_width")
+	opts.SheetMaxHeight = viper.GetInt("sheet_max_height")
+	opts.SheetAspect = viper.GetString("sheet_aspect")
+	opts.Layout = viper.GetString("layout")
+	if viper.GetString("featured") != "" {
+		opts.Featured = strings.Split(viper.GetString("featured"), ",")
+	}
+	opts.HeroSize = viper.GetString("hero_size")
+	opts.Interval = viper.GetString("interval")
+	opts.From = viper.GetString("from")
+	opts.To = viper.GetString("end")
+	opts.SkipCredits = viper.GetBool("skip_credits")
+	opts.SkipIntro = viper.GetBool("skip_intro")
+	opts.Fast = viper.GetBool("fast")
+	opts.Workers = viper.GetInt("workers")
+	opts.FastScale = viper.GetBool("fast_scale")
+	opts.AutoCrop = viper.GetBool("auto_crop")
+	opts.Select = viper.GetString("select")
+	opts.SceneSamples = viper.GetInt("scene_samples")
+	opts.ChapterCaps = viper.GetInt("chapter_caps")
+	if viper.GetString("range") != "" {
+		opts.Ranges = strings.Split(viper.GetString("range"), ",")
+	}
+	if viper.GetString("at") != "" {
+		opts.At = strings.Split(viper.GetString("at"), ",")
+	}
+	opts.Font = viper.GetString("font_all")
+	opts.FontSize = viper.GetInt("font_size")
+	opts.DisableTimestamps = viper.GetBool("disable_timestamps")
+	opts.TimestampOpacity = viper.GetFloat64("timestamp_opacity")
+	opts.TimestampFormat = viper.GetString("timestamp_format")
+	opts.SingleImages = viper.GetBool("single_images")
+	opts.BgContent = getImageColor(viper.GetString("bg_content"), []int{0, 0, 0})
+	opts.BgHeader = getImageColor(viper.GetString("bg_header"), []int{0, 0, 0})
+	opts.FgHeader = getImageColor(viper.GetString("fg_header"), []int{255, 255, 255})
+	opts.Header = viper.GetBool("header")
+	opts.HeaderMeta = viper.GetBool("header_meta")
+	opts.HeaderImage = viper.GetString("header_image")
+	opts.Comment = viper.GetString("comment")
+	opts.Watermark = viper.GetString("watermark")
+	opts.WatermarkAll = viper.GetString("watermark_all")
+	opts.Filters = strings.Split(viper.GetString("filter"), ",")
+	opts.FancyMinAngle = viper.GetInt("fancy_min_angle")
+	opts.FancyMaxAngle = viper.GetInt("fancy_max_angle")
+	opts.Seed = int64(viper.GetInt("seed"))
+	opts.SkipBlank = viper.GetBool("skip_blank")
+	opts.SkipBlurry = viper.GetBool("skip_blurry")
+	opts.SFW = viper.GetBool("sfw")
+	opts.Censor = viper.GetString("censor")
+	opts.CensorArea = viper.GetString("censor_area")
+	opts.CensorLabel = viper.GetString("censor_label")
+	opts.BlurThreshold = viper.GetFloat64("blur_threshold")
+	opts.BlankThreshold = viper.GetInt("blank_threshold")
+	opts.SkipDuplicates = viper.GetBool("skip_duplicates")
+	opts.DuplicateThreshold = viper.GetInt("duplicate_threshold")
+	for _, r := range viper.GetStringSlice("reject") {
+		opts.Reject = append(opts.Reject, strings.Split(r, ",")...)
+	}
+	opts.AnalyzeSamples = viper.GetInt("analyze_samples")
+	opts.Retries = viper.GetInt("retries")
+	opts.RetryStep = viper.GetString("retry_step")
+	opts.RetryDirection = viper.GetString("retry_direction")
+	opts.RetryFallback = viper.GetString("retry_fallback")
+	opts.Candidates = viper.GetInt("candidates")
+	opts.CandidateStep = viper.GetString("candidate_step")
+	return opts
 }
 
-// generates screenshots and returns a list of images
-func GenerateScreenshots(fn string) []image.Image {
-	var thumbnails []image.Image
-	gen, err := screengen.NewGenerator(fn)
-	if err != nil {
-		log.Fatalf("Error reading video file: %v", err)
-	}
-
-	if viper.GetBool("fast") {
-		gen.Fast = true
-	}
-
-	defer gen.Close()
-
-	// truncate duration to full seconds
-	// this prevents empty/black images when the movie is some milliseconds longer
-	// ffmpeg then sometimes takes a black screenshot AFTER the movie finished for some reason
-	duration := 1000 * (gen.Duration / 1000)
-	from := stringToMS(viper.GetString("from"))
-	end := stringToMS(viper.GetString("end"))
-
-	if from > 0 {
-		log.Infof("First screenshot will be at %s", viper.GetString("from"))
-	}
-	if end > 0 && from < end {
-		log.Infof("Last screenshot will be at %s", viper.GetString("end"))
-	}
-
-	if viper.GetBool("skip_credits") {
-		percentage := int64((float32(duration / 100)) * (5.5 * 2))
-		//cut of 2 minutes of video if video has at least 4 minutes else cut away (or at least 10.10%)
-		if duration > (120000*2) && 120000 > percentage {
-			duration = duration - 120000
-		} else {
-			duration = duration - percentage
-		}
-	}
-
-	if end > 0 {
-		if strings.HasPrefix(viper.GetString("end"), "-") {
-			log.Infof("to option is negative, substracting %s from end of file", viper.GetString("end"))
-			duration = duration - end
-		} else {
-			duration = end
-		}
-	}
-
-	if from > 0 {
-		duration = duration - from
-	}
-
-	numcaps = viper.GetInt("numcaps")
-	if viper.GetInt("interval") > 0 {
-		var durationSec = duration / 1000
-		var intervalSec = int64(viper.GetInt("interval"))
-		if durationSec < intervalSec {
-			log.Fatalf("Specified interval is longer than video duration, " +
-				"use smaller interval or set numcaps instead.")
-		}
-		numcaps = int(durationSec / intervalSec)
-		log.Debugf("interval option set, numcaps are set to %d", numcaps)
-		viper.Set("columns", int(math.Sqrt(float64(numcaps))))
-	}
-
-	inc := duration / (int64(numcaps))
-
-	if end > 0 && from > 0 && numcaps > 1 {
-		inc = duration / (int64(numcaps) - 1)
-	}
-
-	if inc <= 60000 {
-		log.Warn("very small timestamps in use... consider decreasing numcaps")
-	}
-	if inc <= 9000 {
-		log.Errorf("interval (%ds) is way to small (less then 9s), please decrease numcaps", inc/1000)
-	}
-
-	d := inc
-
-	if viper.GetInt("interval") > 0 {
-		d = (int64(viper.GetInt("interval")) * 1000)
-	}
-
-	if from > 0 {
-		d = from
-	}
-
-	if viper.GetBool("vtt") {
-		timestamps = append(timestamps, "00:00:00")
-	}
-
-	for i := 0; i < numcaps; i++ {
-		stamp := d
-		img, err := gen.Image(d)
-		if err != nil {
-			log.Fatalf("Can't generate screenshot: %v", err)
-		}
-
-		// should we skip any images?
-		if viper.GetBool("skip_blank") || viper.GetBool("skip_blurry") || viper.GetBool("sfw") {
-			maxCount := 3
-			count := 1
-			for skipImage(img) == true && maxCount >= count {
-				log.Warnf("[%d/%d] frame skipped based on settings at: %s retry at: %s", count, maxCount, fmt.Sprintf(time.Unix(stamp/1000, 0).UTC().Format("15:04:05")), fmt.Sprintf(time.Unix((stamp+10000)/1000, 0).UTC().Format("15:04:05")))
-				if stamp >= duration-inc {
-					log.Error("end of clip reached... no more blank frames can be skipped")
-					i = numcaps - 1
-					break
-				}
-				stamp = d + (10000 * int64(count))
-				img, _ = gen.Image(stamp)
-				count = count + 1
-			}
-		}
-
-		// if we skipped ahead of next frame...
-		if stamp > d {
-			d = stamp
-		}
-
-		timestamp := fmt.Sprintf(time.Unix(stamp/1000, 0).UTC().Format("15:04:05"))
-		log.Infof("generating screenshot %02d/%02d at %s", i+1, numcaps, timestamp)
-		if viper.GetBool("vtt") {
-			timestamps = append(timestamps, timestamp)
-		}
-		//var thumb image.Image
-		if viper.GetInt("width") > 0 {
-			img = imaging.Resize(img, viper.GetInt("width"), 0, imaging.Lanczos)
-		} else if viper.GetInt("width") == 0 && viper.GetInt("height") > 0 {
-			img = imaging.Resize(img, 0, viper.GetInt("height"), imaging.Lanczos)
-		}
-
-		//apply filters
-		filters := strings.Split(viper.GetString("filter"), ",")
-		for _, filter := range filters {
-			switch filter {
-			case "greyscale":
-				img = imaging.Grayscale(img)
-				img = imaging.Sharpen(img, 1.0)
-				img = imaging.AdjustContrast(img, 20)
-				log.Debug("greyscale filter applied")
-			case "invert":
-				img = imaging.Invert(img)
-				log.Debug("invert filter applied")
-			case "fancy":
-				//TODO: find a way to do this without GIFT...
-				log.Debug("fancy filter applied")
-				//draw timestamp to the image before rotating it!
-				tsimage := drawTimestamp(timestamp)
-				img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), viper.GetFloat64("timestamp_opacity"))
-
-				g := gift.New(
-					gift.Rotate(randomInt(-10, 15), getImageColor(viper.GetString("bg_content"), []int{0, 0, 0}), gift.CubicInterpolation),
-				)
-				dst := image.NewRGBA(g.Bounds(img.Bounds()))
-				g.Draw(dst, img)
-				img = dst
-				viper.Set("disable_timestamps", true)
-			case "sepia":
-				log.Debug("sepia filter applied")
-				g := gift.New(
-					gift.Sepia(100),
-				)
-				dst := image.NewRGBA(g.Bounds(img.Bounds()))
-				g.Draw(dst, img)
-				img = dst
-			case "cross":
-				log.Debug("cross filter applied")
-				img = CrossProcessingFilter(img, 0.5, 9)
-			case "strip":
-				log.Debug("image stip filter applied")
-				//draw timestamp!
-				tsimage := drawTimestamp(timestamp)
-				img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), viper.GetFloat64("timestamp_opacity"))
-				viper.Set("disable_timestamps", true)
-				img = ImageStripFilter(img)
-			}
-		}
-
-		if !viper.GetBool("disable_timestamps") && !viper.GetBool("single_images") {
-			log.Debug("adding timestamp to image")
-			tsimage := drawTimestamp(timestamp)
-			img = imaging.Overlay(img, tsimage, image.Pt(img.Bounds().Dx()-tsimage.Bounds().Dx()-10, img.Bounds().Dy()-tsimage.Bounds().Dy()-10), viper.GetFloat64("timestamp_opacity"))
-		}
-
-		//watermark middle image
-		if i == (numcaps-1)/2 && viper.GetString("watermark") != "" && !viper.GetBool("single_images") {
-			ov, err := imaging.Open(viper.GetString("watermark"))
-			if err == nil {
-				if ov.Bounds().Dx() > img.Bounds().Dx() {
-					ov = imaging.Resize(ov, img.Bounds().Dx(), 0, imaging.Lanczos)
-				}
-				if ov.Bounds().Dy() > img.Bounds().Dy() {
-					ov = imaging.Resize(ov, 0, img.Bounds().Dy(), imaging.Lanczos)
-				}
-				posX := (img.Bounds().Dx() - ov.Bounds().Dx()) / 2
-				posY := (img.Bounds().Dy() - ov.Bounds().Dy()) / 2
-				img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
-			}
-		} else if viper.GetString("watermark") != "" && viper.GetBool("single_images") {
-			ov, err := imaging.Open(viper.GetString("watermark"))
-			if err == nil {
-				if ov.Bounds().Dx() > img.Bounds().Dx() {
-					ov = imaging.Resize(ov, img.Bounds().Dx(), 0, imaging.Lanczos)
-				}
-				if ov.Bounds().Dy() > img.Bounds().Dy() {
-					ov = imaging.Resize(ov, 0, img.Bounds().Dy(), imaging.Lanczos)
-				}
-				posX := (img.Bounds().Dx() - ov.Bounds().Dx()) / 2
-				posY := (img.Bounds().Dy() - ov.Bounds().Dy()) / 2
-				img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
-			}
-		}
-
-		if viper.GetString("watermark_all") != "" {
-			ov, err := imaging.Open(viper.GetString("watermark_all"))
-			if err == nil {
-				if ov.Bounds().Dx() > (img.Bounds().Dx() / 4) {
-					ov = imaging.Resize(ov, (img.Bounds().Dx() / 4), 0, imaging.Lanczos)
-				}
-				if ov.Bounds().Dy() > (img.Bounds().Dy() / 4) {
-					ov = imaging.Resize(ov, 0, (img.Bounds().Dy() / 4), imaging.Lanczos)
-				}
-				//default position for watermarking is bottom-left
-				posX := 10
-				posY := img.Bounds().Dy() - ov.Bounds().Dy() - 10
-				img = imaging.Overlay(img, ov, image.Pt(posX, posY), 0.6)
-			}
-		}
-
-		if viper.GetBool("single_images") {
+// saves the generated images (and vtt file) for movie
+func saveResult(res *contactsheet.Result, movie string, logger log.FieldLogger) error {
+	if viper.GetBool("single_images") {
+		for i, frame := range res.Frames {
 			var fname string
-			if numcaps == 1 {
-				fname = getSavePath(mpath, 0)
+			if len(res.Frames) == 1 {
+				fname = reserveSavePath(movie, 0)
 			} else {
-				fname = getSavePath(mpath, i+1)
+				fname = reserveSavePath(movie, i+1)
 			}
 			createTargetDirs(fname)
-			imaging.Save(img, fname)
-
-			uploadFile(fname)
-
-		} else {
-			thumbnails = append(thumbnails, img)
-		}
-
-		if viper.GetInt("interval") > 0 {
-			d += (int64(viper.GetInt("interval")) * 1000)
-		} else {
-			d += inc
-		}
-	}
-
-	return thumbnails
-}
-
-func makeContactSheet(thumbs []image.Image, fn string) {
-	log.Info("Composing Contact Sheet")
-	imgWidth := thumbs[0].Bounds().Dx()
-	imgHeight := thumbs[0].Bounds().Dy()
-
-	columns := viper.GetInt("columns")
-	imgRows := int(math.Ceil(float64(len(thumbs)) / float64(columns)))
-
-	log.Debugf("single image dimension: %dx%d", imgWidth, imgHeight)
-	log.Debugf("new image dimension: %dx%d", imgWidth*columns, imgHeight*imgRows)
-
-	paddingColumns := 0
-	singlepadd := 0
-	paddingRows := 0
-	if viper.GetInt("padding") > 0 {
-		paddingColumns = (columns + 1) * viper.GetInt("padding")
-		paddingRows = (imgRows + 1) * viper.GetInt("padding")
-		singlepadd = viper.GetInt("padding")
-	}
-
-	// create a new blank image
-	bgColor := getImageColor(viper.GetString("bg_content"), []int{0, 0, 0})
-	dst := imaging.New(imgWidth*columns+paddingColumns, imgHeight*imgRows+paddingRows, bgColor)
-	var head image.Image
-	x := 0
-	curRow := 0
-	headerHeight := 0
-
-	if viper.GetBool("header") {
-		log.Info("creating header information")
-		head = appendHeader(dst)
-		headerHeight = head.Bounds().Dy()
-	}
-
-	vttContent := "WEBVTT\n"
-	// paste thumbnails into the new image side by side with padding if enabled
-	for idx, thumb := range thumbs {
-
-		if x >= columns {
-			x = 0
-			curRow = curRow + 1
-		}
-
-		xPos := (x * imgWidth) + singlepadd
-		yPos := (curRow * imgHeight) + singlepadd
-
-		if x >= 0 && x <= columns {
-			xPos = (x * imgWidth) + (singlepadd * x) + singlepadd
-		}
-
-		if curRow >= 0 && curRow < imgRows {
-			yPos = (curRow * imgHeight) + (singlepadd * curRow) + singlepadd
-		}
-
-		dst = imaging.Paste(dst, thumb, image.Pt(xPos, yPos))
-		x = x + 1
+			if err := imaging.Save(frame.Image, fname); err != nil {
+				return fmt.Errorf("error saveing image: %v", err)
+			}
 
-		if viper.GetBool("vtt") {
-			_, imgName := filepath.Split(fn)
-			vttContent = fmt.Sprintf("%s\n%s.000 --> %s.000\n%s#xywh=%d,%d,%d,%d\n", vttContent, timestamps[idx], timestamps[idx+1], imgName, xPos, yPos+headerHeight, imgWidth, imgHeight)
+			if err := uploadFile(fname, logger); err != nil {
+				return err
+			}
 		}
-
+		return nil
 	}
 
-	if viper.GetBool("header") {
-		newIm := imaging.New(dst.Bounds().Dx(), dst.Bounds().Dy()+head.Bounds().Dy(), bgColor)
-		dst = imaging.Paste(newIm, dst, image.Pt(0, head.Bounds().Dy()))
-		dst = imaging.Paste(dst, head, image.Pt(0, 0))
+	if res.Sheet == nil {
+		return nil
 	}
 
 	// save the combined image to file
+	fn := reserveSavePath(movie, 0)
 	createTargetDirs(fn)
-	err := imaging.Save(dst, fn)
+	err := imaging.Save(res.Sheet, fn)
 	if err != nil {
-		log.Fatalf("error saveing image: %v", err)
+		return fmt.Errorf("error saveing image: %v", err)
 	}
-	log.Infof("Saved image to %s", fn)
+	logger.Infof("Saved image to %s", fn)
 	if viper.GetBool("vtt") {
+		_, imgName := filepath.Split(fn)
 		vttfn := strings.Replace(fn, filepath.Ext(fn), ".vtt", -1)
-		err = ioutil.WriteFile(vttfn, []byte(vttContent), 0644)
+		err = ioutil.WriteFile(vttfn, []byte(res.WebVTT(imgName)), 0644)
 		if err != nil {
-			log.Fatalf("error saveing vtt file: %v", err)
+			return fmt.Errorf("error saveing vtt file: %v", err)
 		}
-		log.Infof("Saved vtt to %s", vttfn)
+		logger.Infof("Saved vtt to %s", vttfn)
 	}
 
-	uploadFile(fn)
-
+	return uploadFile(fn, logger)
 }
 
-func appendHeader(im image.Image) image.Image {
-	var timestamped image.Image
-
-	font, err := freetype.ParseFont(fontBytes)
+// writes the frame scores of movie as json or csv file named like its
+// contact sheet
+func analyzeMovie(movie string, opts contactsheet.Options, logger log.FieldLogger) error {
+	logger.Infof("analyzing %s", movie)
+	opts.Logger = logger
+	res, err := contactsheet.Analyze(context.Background(), movie, opts)
 	if err != nil {
-		log.Errorf("freetype parse error: %v", err)
-		return timestamped
+		return err
 	}
 
-	// TODO: move this to a helper function!
-	bgColor := getImageColor(viper.GetString("bg_header"), []int{0, 0, 0})
-
-	fgColor := getImageColor(viper.GetString("fg_header"), []int{255, 255, 255})
-
-	fontcolor, bg := image.NewUniform(fgColor), image.NewUniform(bgColor)
-	c := freetype.NewContext()
-	c.SetDPI(96)
-	c.SetFont(font)
-	c.SetFontSize(float64(viper.GetInt("font_size")))
-
-	// get width and height of the string and draw an image to hold it
-	//x, y, _ := c.MeasureString(timestamp)
-	header := createHeader(mpath)
-
-	rgba := image.NewNRGBA(image.Rect(0, 0, im.Bounds().Dx(), (5+int(c.PointToFix32(float64(viper.GetInt("font_size")+4))>>8)*len(header))+10))
-	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
-	if viper.GetString("header_image") != "" {
-		ov, err := imaging.Open(viper.GetString("header_image"))
-		if err == nil {
-			if ov.Bounds().Dy() >= (rgba.Bounds().Dy() - 20) {
-				ov = imaging.Resize(ov, 0, rgba.Bounds().Dy()-20, imaging.Lanczos)
-			}
-			//center image inside header
-			posY := (rgba.Bounds().Dy() - ov.Bounds().Dy()) / 2
-			if posY < 10 {
-				posY = 10
-			}
-			rgba = imaging.Overlay(rgba, ov, image.Pt(rgba.Bounds().Dx()-ov.Bounds().Dx()-10, posY), 1.0)
-
-		} else {
-			log.Error("error opening header overlay image")
+	var b []byte
+	ext := viper.GetString("analyze_format")
+	switch ext {
+	case "json":
+		if b, err = json.MarshalIndent(res, "", "    "); err != nil {
+			return err
 		}
+	case "csv":
+		b = []byte(res.CSV())
+	default:
+		return fmt.Errorf("unknown analyze format: %s", ext)
 	}
 
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
-	c.SetSrc(fontcolor)
-
-	for i, s := range header {
-		//draw the text with 10px padding and lineheight +4
-		pt := freetype.Pt(10, (5 + int(c.PointToFix32(float64(viper.GetInt("font_size")+4))>>8)*(i+1)))
-		_, err = c.DrawString(s, pt)
-		if err != nil {
-			fmt.Println(err)
-			return timestamped
-		}
+	// the report describes the current settings, older ones are replaced
+	fn := constructSavePath(movie, 0)
+	fn = strings.TrimSuffix(fn, filepath.Ext(fn)) + "." + ext
+	createTargetDirs(fn)
+	if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+		return fmt.Errorf("error saveing analysis: %v", err)
 	}
-
-	return rgba
+	logger.Infof("Saved analysis to %s", fn)
+	return nil
 }
 
-func createHeader(fn string) []string {
-
-	var header []string
-	var fname, fsize string
-	_, fname = filepath.Split(fn)
-
-	if f, err := os.Open(fn); err == nil {
-		defer f.Close()
-		stat, err := f.Stat()
-		if err != nil {
-			fmt.Println(err)
-		}
-		fsize = humanize.IBytes(uint64(stat.Size()))
-	} else {
-		// try if it is a web video
-		if _, err = url.ParseRequestURI(fn); err != nil {
-			fmt.Println(err)
-		}
-		resp, err := http.Head(fn)
-		if err != nil {
-			fmt.Println(err)
-		}
-		defer resp.Body.Close()
-
-		fsize = resp.Header.Get("Content-Length")
-		if fsize == "" {
-			fsize = "unknown" // too expensive to download the whole file
-		} else {
-			size, _ := strconv.Atoi(fsize)
-			fsize = humanize.IBytes(uint64(size))
-		}
-
-		cdisposition := resp.Header.Get("Content-Disposition")
-		_, params, err := mime.ParseMediaType(cdisposition)
-		if params["filename"] != "" {
-			fname = params["filename"] // prefer filename to the name split from url
-		}
+// generates and saves the contact sheet for a single movie
+func processMovie(movie string, opts contactsheet.Options, logger log.FieldLogger) error {
+	if viper.GetBool("analyze") {
+		return analyzeMovie(movie, opts, logger)
 	}
-	fsize = fmt.Sprintf("File Size: %s", fsize)
-	fname = fmt.Sprintf("File Name: %s", fname)
+	logger.Infof("generating contact sheet for %s", movie)
+	logger.Debugf("image will be saved as %s", getSavePath(movie, 0))
 
-	gen, err := screengen.NewGenerator(fn)
-	if err != nil {
-		log.Errorf("Error reading video file: %v", err)
-		os.Exit(1)
-	}
-	defer gen.Close()
-
-	duration := fmt.Sprintf("Duration: %s", time.Unix(gen.Duration/1000, 0).UTC().Format("15:04:05"))
-
-	dimension := fmt.Sprintf("Resolution: %dx%d", gen.Width(), gen.Height())
-
-	header = append(header, fname)
-	header = append(header, fsize)
-	header = append(header, duration)
-	header = append(header, dimension)
+	// TODO: implement generation of image contac sheets from a folder
 
-	if viper.GetBool("header_meta") {
-		header = append(header, fmt.Sprintf("FPS: %.2f, Bitrate: %dKbp/s", gen.FPS, gen.Bitrate))
-		header = append(header, fmt.Sprintf("Codec: %s / %s", gen.VideoCodecLongName, gen.AudioCodecLongName))
+	//skip existing image if option is present
+	cnt := 0
+	if viper.GetBool("single_images") {
+		cnt = 1
 	}
 
-	if viper.GetString("comment") != "" {
-		header = append(header, fmt.Sprintf("%s", viper.GetString("comment")))
+	if fileExists(getSavePath(movie, cnt)) && viper.GetBool("skip_existing") {
+		logger.Infof("file already exists, skipping %s", getSavePath(movie, 0))
+		return nil
 	}
 
-	return header
+	opts.Logger = logger
+	res, err := contactsheet.Generate(context.Background(), movie, opts)
+	if err != nil {
+		return err
+	}
+	return saveResult(res, movie, logger)
 }
 
 func main() {
@@ -564,10 +227,17 @@ func main() {
 	viper.SetDefault("padding", 10)
 	viper.SetDefault("width", 400)
 	viper.SetDefault("height", 0)
+	viper.SetDefault("sheet_width", 0)
+	viper.SetDefault("sheet_max_height", 0)
+	viper.SetDefault("sheet_aspect", "")
+	viper.SetDefault("layout", "grid")
+	viper.SetDefault("featured", "best")
+	viper.SetDefault("hero_size", "2x2")
 	viper.SetDefault("font_all", "DroidSans.ttf")
 	viper.SetDefault("font_size", 12)
 	viper.SetDefault("disable_timestamps", false)
 	viper.SetDefault("timestamp_opacity", 1.0)
+	viper.SetDefault("timestamp_format", "hms")
 	viper.SetDefault("filename", "{{.Path}}{{.Name}}.jpg")
 	viper.SetDefault("verbose", false)
 	viper.SetDefault("bg_content", "0,0,0")
@@ -585,21 +255,50 @@ func main() {
 	viper.SetDefault("comment", "contactsheet created with mt (https://github.com/mutschler/mt)")
 	viper.SetDefault("watermark-all", "")
 	viper.SetDefault("filter", "none")
+	viper.SetDefault("fancy_min_angle", -10)
+	viper.SetDefault("fancy_max_angle", 15)
+	viper.SetDefault("seed", 0)
 	viper.SetDefault("skip_blank", false)
 	viper.SetDefault("skip_blurry", false)
 	viper.SetDefault("skip_existing", false)
 	viper.SetDefault("overwrite", false)
 	viper.SetDefault("sfw", false)
+	viper.SetDefault("censor", "")
+	viper.SetDefault("censor_area", "frame")
+	viper.SetDefault("censor_label", "")
 	viper.SetDefault("fast", false)
+	viper.SetDefault("fast_scale", false)
+	viper.SetDefault("auto_crop", false)
 	viper.SetDefault("show_config", false)
 	viper.SetDefault("webvtt", false)
 	viper.SetDefault("vtt", false)
-	viper.SetDefault("blur_threshold", 62)
+	viper.SetDefault("blur_threshold", 25)
 	viper.SetDefault("blank_threshold", 85)
+	viper.SetDefault("skip_duplicates", false)
+	viper.SetDefault("duplicate_threshold", 6)
+	viper.SetDefault("reject", []string{})
+	viper.SetDefault("analyze", false)
+	viper.SetDefault("analyze_format", "json")
+	viper.SetDefault("analyze_samples", 0)
+	viper.SetDefault("retries", 3)
+	viper.SetDefault("retry_step", "10s")
+	viper.SetDefault("retry_direction", "forward")
+	viper.SetDefault("retry_fallback", "last")
+	viper.SetDefault("candidates", 1)
+	viper.SetDefault("candidate_step", "1s")
 	viper.SetDefault("upload", false)
 	viper.SetDefault("upload_url", "http://example.com/upload")
 	viper.SetDefault("skip_credits", false)
-	viper.SetDefault("interval", 0)
+	viper.SetDefault("skip_intro", false)
+	viper.SetDefault("interval", "0")
+	viper.SetDefault("select", "even")
+	viper.SetDefault("scene_samples", 0)
+	viper.SetDefault("chapter_caps", 1)
+	viper.SetDefault("range", "")
+	viper.SetDefault("at", "")
+	viper.SetDefault("at_file", "")
+	viper.SetDefault("workers", 0)
+	viper.SetDefault("jobs", 1)
 
 	viper.RegisterAlias("to", "end")
 
@@ -615,6 +314,24 @@ func main() {
 	flag.IntP("width", "w", viper.GetInt("width"), "width of a single screenshot in px")
 	viper.BindPFlag("width", flag.Lookup("width"))
 
+	flag.Int("sheet-width", viper.GetInt("sheet_width"), "width of the contact sheet in px, computes columns and width of the screenshots automatically")
+	viper.BindPFlag("sheet_width", flag.Lookup("sheet-width"))
+
+	flag.Int("sheet-max-height", viper.GetInt("sheet_max_height"), "maximum height of the contact sheet (without header) in px for --sheet-width")
+	viper.BindPFlag("sheet_max_height", flag.Lookup("sheet-max-height"))
+
+	flag.String("sheet-aspect", viper.GetString("sheet_aspect"), "aspect ratio of the contact sheet (like 16:9 or 1.5) for --sheet-width")
+	viper.BindPFlag("sheet_aspect", flag.Lookup("sheet-aspect"))
+
+	flag.String("layout", viper.GetString("layout"), "layout of the contact sheet: grid or hero (enlarges the --featured screenshots)")
+	viper.BindPFlag("layout", flag.Lookup("layout"))
+
+	flag.String("featured", viper.GetString("featured"), "comma separated list of screenshots enlarged by --layout=hero: first, middle, last, best or a number")
+	viper.BindPFlag("featured", flag.Lookup("featured"))
+
+	flag.String("hero-size", viper.GetString("hero_size"), "size of the featured screenshots: 2x2 or row")
+	viper.BindPFlag("hero_size", flag.Lookup("hero-size"))
+
 	flag.StringP("font", "f", viper.GetString("font_all"), "font to use for timestamps and header information")
 	viper.BindPFlag("font_all", flag.Lookup("font"))
 
@@ -624,6 +341,9 @@ func main() {
 	flag.BoolP("disable-timestamps", "d", viper.GetBool("disable_timestamps"), "disable timestamps on images")
 	viper.BindPFlag("disable_timestamps", flag.Lookup("disable-timestamps"))
 
+	flag.String("timestamp-format", viper.GetString("timestamp_format"), "format of the timestamps: hms (HH:MM:SS), hms.ms (HH:MM:SS.mmm), smpte (HH:MM:SS:FF), seconds or percent")
+	viper.BindPFlag("timestamp_format", flag.Lookup("timestamp-format"))
+
 	flag.BoolP("verbose", "v", viper.GetBool("verbose"), "enable verbose output")
 	viper.BindPFlag("verbose", flag.Lookup("verbose"))
 
@@ -651,10 +371,10 @@ func main() {
 	flag.String("watermark-all", viper.GetString("watermark_all"), "watermark every single image")
 	viper.BindPFlag("watermark_all", flag.Lookup("watermark-all"))
 
-	flag.BoolP("skip-blank", "b", viper.GetBool("skip_blank"), "skip up to 3 images in a row which seem to be blank (can slow mt down)")
+	flag.BoolP("skip-blank", "b", viper.GetBool("skip_blank"), "skip up to --retries images in a row which seem to be blank (can slow mt down)")
 	viper.BindPFlag("skip_blank", flag.Lookup("skip-blank"))
 
-	flag.Bool("skip-blurry", viper.GetBool("skip_blurry"), "skip up to 3 images in a row which seem to be blurry (can slow mt down)")
+	flag.Bool("skip-blurry", viper.GetBool("skip_blurry"), "skip up to --retries images in a row which seem to be blurry (can slow mt down)")
 	viper.BindPFlag("skip_blurry", flag.Lookup("skip-blurry"))
 
 	flag.Bool("version", false, "show version number and exit")
@@ -672,13 +392,22 @@ func main() {
 	flag.Bool("filters", false, "list all available image filters")
 	viper.BindPFlag("filters", flag.Lookup("filters"))
 
+	flag.Int("fancy-min-angle", viper.GetInt("fancy_min_angle"), "minimum angle in degrees the fancy filter rotates images by (defaults to -10)")
+	viper.BindPFlag("fancy_min_angle", flag.Lookup("fancy-min-angle"))
+
+	flag.Int("fancy-max-angle", viper.GetInt("fancy_max_angle"), "maximum angle in degrees the fancy filter rotates images by (defaults to 15)")
+	viper.BindPFlag("fancy_max_angle", flag.Lookup("fancy-max-angle"))
+
+	flag.Int64("seed", int64(viper.GetInt("seed")), "seed for random decisions like the fancy rotation, 0 uses a hash of the file path so every run creates the same image")
+	viper.BindPFlag("seed", flag.Lookup("seed"))
+
 	flag.String("output", viper.GetString("filename"), "set an output filename")
 	viper.BindPFlag("filename", flag.Lookup("output"))
 
-	flag.String("from", viper.GetString("from"), "set starting point in format HH:MM:SS")
+	flag.String("from", viper.GetString("from"), "set starting point as HH:MM:SS, seconds, duration (1h2m) or percentage (5%), negative values count from the end")
 	viper.BindPFlag("from", flag.Lookup("from"))
 
-	flag.String("to", viper.GetString("end"), "set end point in format HH:MM:SS")
+	flag.String("to", viper.GetString("end"), "set end point as HH:MM:SS, seconds, duration (1h2m) or percentage (95%), negative values count from the end")
 	viper.BindPFlag("end", flag.Lookup("to"))
 
 	flag.String("save-config", viper.GetString("save_config"), "save config with current settings to this file")
@@ -696,36 +425,114 @@ func main() {
 	flag.Bool("sfw", viper.GetBool("sfw"), "use nudity detection to generate sfw images (HIGHLY EXPERIMENTAL)")
 	viper.BindPFlag("sfw", flag.Lookup("sfw"))
 
+	flag.String("censor", viper.GetString("censor"), "blur or pixelate images flagged by the nudity detection instead of using them as they are: blur or pixelate")
+	viper.BindPFlag("censor", flag.Lookup("censor"))
+
+	flag.String("censor-area", viper.GetString("censor_area"), "part of a flagged image that is censored: frame or skin")
+	viper.BindPFlag("censor_area", flag.Lookup("censor-area"))
+
+	flag.String("censor-label", viper.GetString("censor_label"), "text drawn in the middle of censored images")
+	viper.BindPFlag("censor_label", flag.Lookup("censor-label"))
+
 	flag.Bool("show-config", viper.GetBool("show_config"), "show path to currently used config file as well as used values and exit")
 	viper.BindPFlag("show_config", flag.Lookup("show-config"))
 
 	flag.Bool("fast", viper.GetBool("fast"), "inacurate but faster seeking")
 	viper.BindPFlag("fast", flag.Lookup("fast"))
 
+	flag.Bool("fast-scale", viper.GetBool("fast_scale"), "let the decoder scale frames down before resizing them, a lot faster for 4K and bigger videos")
+	viper.BindPFlag("fast_scale", flag.Lookup("fast-scale"))
+
+	flag.Bool("auto-crop", viper.GetBool("auto_crop"), "detect black bars (letterbox/pillarbox) and crop them from the screencaps")
+	viper.BindPFlag("auto_crop", flag.Lookup("auto-crop"))
+
 	flag.Bool("webvtt", viper.GetBool("webvtt"), "create a .vtt file: disables header, header-meta, padding and timestamps")
 	viper.BindPFlag("webvtt", flag.Lookup("webvtt"))
 
 	flag.Bool("vtt", viper.GetBool("vtt"), "create a .vtt file for the generated image")
 	viper.BindPFlag("vtt", flag.Lookup("vtt"))
 
-	flag.Int("blur-threshold", viper.GetInt("blur_threshold"), "set a custom threshold to use for blurry image detection (defaults to 62)")
+	flag.Float64("blur-threshold", viper.GetFloat64("blur_threshold"), "minimum sharpness (variance of the laplacian, see mt -v) of an image, less is considered blurry (defaults to 25)")
 	viper.BindPFlag("blur_threshold", flag.Lookup("blur-threshold"))
 
 	flag.Int("blank-threshold", viper.GetInt("blank_threshold"), "set a custom threshold to use for blank image detection (defaults to 85)")
 	viper.BindPFlag("blank_threshold", flag.Lookup("blank-threshold"))
 
+	flag.Bool("skip-duplicates", viper.GetBool("skip_duplicates"), "skip up to --retries images in a row which look like an earlier image (can slow mt down)")
+	viper.BindPFlag("skip_duplicates", flag.Lookup("skip-duplicates"))
+
+	flag.Int("duplicate-threshold", viper.GetInt("duplicate_threshold"), "set a custom threshold (0-64) to use for duplicate image detection (defaults to 6)")
+	viper.BindPFlag("duplicate_threshold", flag.Lookup("duplicate-threshold"))
+
+	flag.String("reject", strings.Join(viper.GetStringSlice("reject"), ","), "comma separated chain of rejectors with optional threshold (blank:80,blurry:20,nude,duplicate:6), overwrites --skip-blank, --skip-blurry, --sfw and --skip-duplicates")
+	viper.BindPFlag("reject", flag.Lookup("reject"))
+
+	flag.Bool("analyze", viper.GetBool("analyze"), "don't create a contact sheet but write the blank, blur, nudity, brightness and duplicate scores of every frame to a .json or .csv file to calibrate the thresholds")
+	viper.BindPFlag("analyze", flag.Lookup("analyze"))
+
+	flag.String("analyze-format", viper.GetString("analyze_format"), "format of the --analyze report: json or csv")
+	viper.BindPFlag("analyze_format", flag.Lookup("analyze-format"))
+
+	flag.Int("analyze-samples", viper.GetInt("analyze_samples"), "number of frames spread over the video by --analyze, 0 analyzes the frames of the contact sheet")
+	viper.BindPFlag("analyze_samples", flag.Lookup("analyze-samples"))
+
+	flag.Int("retries", viper.GetInt("retries"), "maximum number of frames tried for a skipped image (defaults to 3)")
+	viper.BindPFlag("retries", flag.Lookup("retries"))
+
+	flag.String("retry-step", viper.GetString("retry_step"), "distance between retries as time value or percentage of the distance between screencaps (defaults to 10s)")
+	viper.BindPFlag("retry_step", flag.Lookup("retry-step"))
+
+	flag.String("retry-direction", viper.GetString("retry_direction"), "direction to search for a better frame: forward, backward or alternate (defaults to forward)")
+	viper.BindPFlag("retry_direction", flag.Lookup("retry-direction"))
+
+	flag.String("retry-fallback", viper.GetString("retry_fallback"), "frame used if all retries are skipped: last or best (highest quality) (defaults to last)")
+	viper.BindPFlag("retry_fallback", flag.Lookup("retry-fallback"))
+
+	flag.Int("candidates", viper.GetInt("candidates"), "number of frames taken around every timestamp, the one with the best quality is used (defaults to 1)")
+	viper.BindPFlag("candidates", flag.Lookup("candidates"))
+
+	flag.String("candidate-step", viper.GetString("candidate_step"), "distance between candidates as time value or percentage of the distance between screencaps (defaults to 1s)")
+	viper.BindPFlag("candidate_step", flag.Lookup("candidate-step"))
+
 	flag.Bool("upload", viper.GetBool("upload"), "post file via http form submit")
 	viper.BindPFlag("upload", flag.Lookup("upload"))
 
 	flag.String("upload-url", viper.GetString("upload_url"), "url to use for --upload")
 	viper.BindPFlag("upload_url", flag.Lookup("upload-url"))
 
-	flag.IntP("interval", "i", viper.GetInt("interval"), "interval in seconds to take screencaps from, overwrites numcaps (defaults to 0)")
+	flag.StringP("interval", "i", viper.GetString("interval"), "interval to take screencaps from as seconds, HH:MM:SS, duration (1m30s) or percentage, overwrites numcaps (defaults to 0)")
 	viper.BindPFlag("interval", flag.Lookup("interval"))
 
-	flag.Bool("skip-credits", viper.GetBool("skip_credits"), "tries to skip ending credits from screencap creation by cutting off 4 minutes or 10 percent of the clip (defaults to false)")
+	flag.String("select", viper.GetString("select"), "how to select frames: even (same distance between all screencaps), scenes (one screencap per detected scene) or chapters (screencaps per chapter of the file)")
+	viper.BindPFlag("select", flag.Lookup("select"))
+
+	flag.Int("scene-samples", viper.GetInt("scene_samples"), "number of frames to scan for scene changes with --select=scenes, 0 uses 20 per screencap but at least 200")
+	viper.BindPFlag("scene_samples", flag.Lookup("scene-samples"))
+
+	flag.Int("chapter-caps", viper.GetInt("chapter_caps"), "number of screencaps per chapter with --select=chapters (defaults to 1)")
+	viper.BindPFlag("chapter_caps", flag.Lookup("chapter-caps"))
+
+	flag.String("range", viper.GetString("range"), "comma separated list of time ranges (start-end, e.g. 00:10:00-00:20:00) to spread the screencaps over, overwrites from and to")
+	viper.BindPFlag("range", flag.Lookup("range"))
+
+	flag.String("at", viper.GetString("at"), "comma separated list of timestamps (HH:MM:SS.ms, seconds, duration or percentage like 25%) to take screencaps at, overwrites numcaps and interval")
+	viper.BindPFlag("at", flag.Lookup("at"))
+
+	flag.String("at-file", viper.GetString("at_file"), "file with one timestamp per line to take screencaps at, same format as --at")
+	viper.BindPFlag("at_file", flag.Lookup("at-file"))
+
+	flag.Bool("skip-credits", viper.GetBool("skip_credits"), "tries to detect the ending credits and skip them from screencap creation (defaults to false)")
 	viper.BindPFlag("skip_credits", flag.Lookup("skip-credits"))
 
+	flag.Bool("skip-intro", viper.GetBool("skip_intro"), "tries to detect an intro (black frames, logos, opening titles) and skip it from screencap creation (defaults to false)")
+	viper.BindPFlag("skip_intro", flag.Lookup("skip-intro"))
+
+	flag.Int("workers", viper.GetInt("workers"), "number of screenshots to extract in parallel, 0 uses one worker per CPU (defaults to 0)")
+	viper.BindPFlag("workers", flag.Lookup("workers"))
+
+	flag.IntP("jobs", "j", viper.GetInt("jobs"), "number of files to process in parallel (defaults to 1)")
+	viper.BindPFlag("jobs", flag.Lookup("jobs"))
+
 	viper.AutomaticEnv()
 
 	viper.SetConfigType("json")
@@ -738,10 +545,8 @@ func main() {
 		flag.PrintDefaults()
 	}
 
-	err := viper.ReadInConfig()
-	if err != nil {
-		fmt.Errorf("error reading config file: %s using default values", err)
-	}
+	// a missing config file is fine, defaults and flags are used then
+	viper.ReadInConfig()
 
 	flag.Parse()
 
@@ -761,7 +566,7 @@ func main() {
 		viper.SetConfigFile(viper.GetString("config_file"))
 		err := viper.ReadInConfig()
 		if err != nil {
-			fmt.Errorf("error reading config file: %s using default values", err)
+			log.Warnf("error reading config file: %s using default values", err)
 		}
 	}
 
@@ -820,11 +625,6 @@ NOTE: fancy has best results if it is applied as last filter!
 	b, _ := json.Marshal(viper.AllSettings())
 	log.Debugf("config values: %s", b)
 
-	fontBytes, err = getFont(viper.GetString("font_all"))
-	if err != nil {
-		log.Warn("unable to load font, disableing timestamps and header")
-	}
-
 	if viper.GetBool("show_config") {
 		if viper.ConfigFileUsed() != "" {
 			log.Infof("Config file used: %s", viper.ConfigFileUsed())
@@ -835,31 +635,58 @@ NOTE: fancy has best results if it is applied as last filter!
 		os.Exit(1)
 	}
 
+	opts := getOptions()
+	if viper.GetString("at_file") != "" {
+		at, err := readTimestampFile(viper.GetString("at_file"))
+		if err != nil {
+			log.Fatalf("error reading timestamp file: %v", err)
+		}
+		opts.At = append(opts.At, at...)
+	}
+	jobs := viper.GetInt("jobs")
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > 1 && opts.Workers == 0 {
+		// share the CPUs between all files processed at once
+		opts.Workers = runtime.NumCPU() / jobs
+		if opts.Workers < 1 {
+			opts.Workers = 1
+		}
+	}
+
+	movies := make(chan string)
+	var failed int32
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for movie := range movies {
+				var logger log.FieldLogger = log.StandardLogger()
+				if jobs > 1 {
+					// prefix all lines so the output of concurrent files can be told apart
+					logger = log.WithField("file", movie)
+				}
+				if err := processMovie(movie, opts, logger); err != nil {
+					logger.Errorf("failed to create contact sheet for %s: %v", movie, err)
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
 	for _, movie := range flag.Args() {
-		mpath = movie
-		log.Infof("generating contact sheet for %s", movie)
-		log.Debugf("image will be saved as %s", getSavePath(movie, 0))
-
-		var thumbs []image.Image
-		// thumbs = getImages(movie)
-		// TODO: implement generation of image contac sheets from a folder
-
-		//skip existing image if option is present
-		cnt := 0
-		if viper.GetBool("single_images") {
-			cnt = 1
-		}
-
-		if fileExists(getSavePath(movie, cnt)) && viper.GetBool("skip_existing") {
-			log.Infof("file already exists, skipping %s", getSavePath(movie, 0))
-			continue
-		}
+		movies <- movie
+	}
+	close(movies)
+	wg.Wait()
 
-		thumbs = GenerateScreenshots(movie)
-		if len(thumbs) > 0 {
-			makeContactSheet(thumbs, getSavePath(movie, 0))
+	if failed > 0 {
+		log.Errorf("%d of %d files failed", failed, len(flag.Args()))
+		if int(failed) == len(flag.Args()) {
+			os.Exit(exitAllFailed)
 		}
-
+		os.Exit(exitSomeFailed)
 	}
 
 }